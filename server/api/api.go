@@ -11,6 +11,7 @@ import (
 	"github.com/hauke96/sigolo"
 	"stm/config"
 	"stm/oauth2"
+	"stm/project"
 	"stm/util"
 )
 
@@ -48,6 +49,16 @@ func Init() error {
 	sigolo.Info("Registered routes for API %s:", version)
 	printRoutes(router_v2_9)
 
+	err := project.InitInvitations()
+	if err != nil {
+		return err
+	}
+
+	err = startAssignmentSweeper()
+	if err != nil {
+		return err
+	}
+
 	router.Methods(http.MethodOptions).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
@@ -56,7 +67,6 @@ func Init() error {
 		w.Header().Set("Access-Control-Allow-Request-Methods", "GET,POST,DELETE,PUT")
 	})
 
-	var err error
 	if config.Conf.IsHttps() {
 		sigolo.Info("Use HTTPS? yes")
 		err = http.ListenAndServeTLS(":"+strconv.Itoa(config.Conf.Port), config.Conf.SslCertFile, config.Conf.SslKeyFile, router)
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"stm/geometry"
 	"stm/oauth2"
+	"stm/permission"
+	"stm/task"
 	"stm/util"
 	"stm/websocket"
 
@@ -15,8 +18,9 @@ import (
 )
 
 type ApiResponse struct {
-	statusCode int
-	data       interface{}
+	statusCode  int
+	data        interface{}
+	contentType string // Only set for responses which are not JSON. The data is then written as it is.
 }
 
 func BadRequestError(err error) *ApiResponse {
@@ -26,6 +30,31 @@ func BadRequestError(err error) *ApiResponse {
 	}
 }
 
+// ServiceError turns an error of a service into a response. Errors caused by invalid requests, task drafts or
+// geometries are bad requests, missing permissions are forbidden and all other errors are internal server errors.
+func ServiceError(err error) *ApiResponse {
+	var draftError *task.DraftError
+	var geometryError *geometry.InvalidGeometryError
+	var requestError *util.InvalidRequestError
+	if errors.As(err, &draftError) || errors.As(err, &geometryError) || errors.As(err, &requestError) {
+		return BadRequestError(err)
+	}
+
+	var permissionError *permission.PermissionError
+	if errors.As(err, &permissionError) {
+		return ForbiddenError(err)
+	}
+
+	return InternalServerError(err)
+}
+
+func ForbiddenError(err error) *ApiResponse {
+	return &ApiResponse{
+		statusCode: http.StatusForbidden,
+		data:       err,
+	}
+}
+
 func InternalServerError(err error) *ApiResponse {
 	return &ApiResponse{
 		statusCode: http.StatusInternalServerError,
@@ -40,6 +69,15 @@ func JsonResponse(data interface{}) *ApiResponse {
 	}
 }
 
+// FileResponse writes the data as it is using the given content type. This is used for non-JSON data like XML files.
+func FileResponse(data []byte, contentType string) *ApiResponse {
+	return &ApiResponse{
+		statusCode:  http.StatusOK,
+		data:        data,
+		contentType: contentType,
+	}
+}
+
 func EmptyResponse() *ApiResponse {
 	return &ApiResponse{
 		statusCode: http.StatusOK,
@@ -118,19 +156,13 @@ func handleRequest(w http.ResponseWriter, r *http.Request, handler func(r *http.
 	// Recover from panic and perform rollback on transaction
 	defer func() {
 		if r := recover(); r != nil {
-			var err error
-			switch r := r.(type) {
-			case error:
-				err = r
-			default:
-				err = fmt.Errorf("%v", r)
-			}
+			err, status := recoveredError(r)
 
 			logger.Err("!! PANIC !! Recover from panic:")
 			logger.Stack(err)
 			logger.Log("%s", debug.Stack())
 
-			util.ResponseInternalError(w, logger, err)
+			util.ErrorResponse(w, logger, err, status)
 		}
 	}()
 
@@ -140,13 +172,10 @@ func handleRequest(w http.ResponseWriter, r *http.Request, handler func(r *http.
 
 	if response.statusCode != http.StatusOK {
 		// Cause panic which will be recovered using the above function. This will then trigger a transaction rollback.
-		panic(response.data.(error))
+		panic(response)
 	}
 
-	if response.data != nil {
-		encoder := json.NewEncoder(w)
-		encoder.Encode(response.data)
-	}
+	writeResponse(w, response)
 }
 
 // handleAuthenticatedRequest gets and verifies the token from the request, creates the context, starts a transaction, manages
@@ -180,19 +209,13 @@ func handleAuthenticatedRequest(w http.ResponseWriter, r *http.Request, handler
 	// Recover from panic and perform rollback on transaction
 	defer func() {
 		if r := recover(); r != nil {
-			var err error
-			switch r := r.(type) {
-			case error:
-				err = r
-			default:
-				err = fmt.Errorf("%v", r)
-			}
+			err, status := recoveredError(r)
 
 			context.Err("!! PANIC !! Recover from panic:")
 			context.Stack(err)
 			context.Log("%s", debug.Stack())
 
-			util.ResponseInternalError(w, context.Logger, err)
+			util.ErrorResponse(w, context.Logger, err, status)
 
 			context.Log("Try to perform rollback")
 			rollbackErr := context.Transaction.Rollback()
@@ -208,7 +231,7 @@ func handleAuthenticatedRequest(w http.ResponseWriter, r *http.Request, handler
 
 	if response.statusCode != http.StatusOK {
 		// Cause panic which will be recovered using the above function. This will then trigger a transaction rollback.
-		panic(response.data.(error))
+		panic(response)
 	}
 
 	// Commit transaction
@@ -219,8 +242,33 @@ func handleAuthenticatedRequest(w http.ResponseWriter, r *http.Request, handler
 	}
 	context.Debug("Committed transaction")
 
-	if response.data != nil {
-		encoder := json.NewEncoder(w)
-		encoder.Encode(response.data)
+	writeResponse(w, response)
+}
+
+// recoveredError turns the value of a recovered panic into an error and the status code of the response. Error
+// responses of handlers keep their status code, every other panic is an internal server error.
+func recoveredError(recovered interface{}) (error, int) {
+	switch r := recovered.(type) {
+	case *ApiResponse:
+		return r.data.(error), r.statusCode
+	case error:
+		return r, http.StatusInternalServerError
+	default:
+		return fmt.Errorf("%v", r), http.StatusInternalServerError
+	}
+}
+
+func writeResponse(w http.ResponseWriter, response *ApiResponse) {
+	if response.data == nil {
+		return
 	}
+
+	if response.contentType != "" {
+		w.Header().Set("Content-Type", response.contentType)
+		w.Write(response.data.([]byte))
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(response.data)
 }
@@ -2,18 +2,23 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/gorilla/mux"
+	geojson "github.com/paulmach/go.geojson"
 	"github.com/pkg/errors"
 	"io"
 	"net/http"
 	"stm/comment"
 	"stm/config"
 	"stm/export"
+	"stm/geometry"
 	"stm/oauth2"
+	"stm/permission"
 	"stm/project"
 	"stm/task"
 	"stm/util"
 	"stm/websocket"
+	"strconv"
 )
 
 func Init_v2_9(router *mux.Router) (*mux.Router, string) {
@@ -23,20 +28,41 @@ func Init_v2_9(router *mux.Router) (*mux.Router, string) {
 
 	r.HandleFunc("/projects", authenticatedTransactionHandler(getProjects_v2_9)).Methods(http.MethodGet)
 	r.HandleFunc("/projects", authenticatedTransactionHandler(addProject_v2_9)).Methods(http.MethodPost)
+	r.HandleFunc("/projects/summaries", authenticatedTransactionHandler(getProjectSummaries_v2_9)).Methods(http.MethodGet)
 	r.HandleFunc("/projects/{id}", authenticatedTransactionHandler(getProject_v2_9)).Methods(http.MethodGet)
 	r.HandleFunc("/projects/{id}", authenticatedTransactionHandler(deleteProjects_v2_9)).Methods(http.MethodDelete)
 	r.HandleFunc("/projects/{id}", authenticatedTransactionHandler(updateProject_v2_9)).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/owner", authenticatedTransactionHandler(transferOwnership_v2_9)).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/boundary", authenticatedTransactionHandler(setProjectBoundary_v2_9)).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/boundary", authenticatedTransactionHandler(removeProjectBoundary_v2_9)).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/statistics", authenticatedTransactionHandler(getProjectStatistics_v2_9)).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/coverage-check", authenticatedTransactionHandler(checkProjectCoverage_v2_9)).Methods(http.MethodGet)
 	r.HandleFunc("/projects/{id}/export", authenticatedTransactionHandler(exportProject_v2_9)).Methods(http.MethodGet)
 	r.HandleFunc("/projects/import", authenticatedTransactionHandler(importProject_v2_9)).Methods(http.MethodPost)
 	r.HandleFunc("/projects/{id}/users", authenticatedTransactionHandler(addUserToProject_v2_9)).Methods(http.MethodPost)
 	r.HandleFunc("/projects/{id}/users", authenticatedTransactionHandler(leaveProject_v2_9)).Methods(http.MethodDelete)
 	r.HandleFunc("/projects/{id}/users/{uid}", authenticatedTransactionHandler(removeUser_v2_9)).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/users/{uid}/role", authenticatedTransactionHandler(setUserRole_v2_9)).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/join", authenticatedTransactionHandler(joinProject_v2_9)).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/invitations", authenticatedTransactionHandler(createInvitation_v2_9)).Methods(http.MethodPost)
 	r.HandleFunc("/projects/{id}/comments", authenticatedTransactionHandler(addProjectComments_v2_9)).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/tasks", authenticatedTransactionHandler(getProjectTasks_v2_9)).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/tasks", authenticatedTransactionHandler(addTasks_v2_9)).Methods(http.MethodPost)
 
+	r.HandleFunc("/invitations", authenticatedTransactionHandler(redeemInvitation_v2_9)).Methods(http.MethodPost)
+
+	r.HandleFunc("/tasks/split", authenticatedTransactionHandler(splitArea_v2_9)).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/merge", authenticatedTransactionHandler(mergeTasks_v2_9)).Methods(http.MethodPost)
 	r.HandleFunc("/tasks/{id}", authenticatedTransactionHandler(getTask_v2_9)).Methods(http.MethodGet)
+	r.HandleFunc("/tasks/{id}", authenticatedTransactionHandler(updateTask_v2_9)).Methods(http.MethodPut)
+	r.HandleFunc("/tasks/{id}", authenticatedTransactionHandler(deleteTask_v2_9)).Methods(http.MethodDelete)
+	r.HandleFunc("/tasks/{id}/history", authenticatedTransactionHandler(getTaskHistory_v2_9)).Methods(http.MethodGet)
+	r.HandleFunc("/tasks/{id}/split", authenticatedTransactionHandler(splitTask_v2_9)).Methods(http.MethodPost)
 	r.HandleFunc("/tasks/{id}/assignedUser", authenticatedTransactionHandler(assignUser_v2_9)).Methods(http.MethodPost)
 	r.HandleFunc("/tasks/{id}/assignedUser", authenticatedTransactionHandler(unassignUser_v2_9)).Methods(http.MethodDelete)
 	r.HandleFunc("/tasks/{id}/processPoints", authenticatedTransactionHandler(setProcessPoints_v2_9)).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/{id}/validate", authenticatedTransactionHandler(validateTask_v2_9)).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/{id}/reject", authenticatedTransactionHandler(rejectTask_v2_9)).Methods(http.MethodPost)
 	r.HandleFunc("/tasks/{id}/comments", authenticatedTransactionHandler(addTaskComments_v2_9)).Methods(http.MethodPost)
 
 	r.HandleFunc("/updates", authenticatedWebsocket(getWebsocketConnection_v2_9))
@@ -57,13 +83,28 @@ func getConfig_v2_9(_ *http.Request, _ *util.Logger) *ApiResponse {
 
 // Get projects
 // @Summary Get all projects for the requesting user.
+// @Description Gets all projects of the requesting user. When a bounding box is given, only projects whose boundary or tasks intersect it are returned.
 // @Version 2.9
 // @Tags projects
 // @Produce json
+// @Param bbox query string false "Bounding box of the form 'minLon,minLat,maxLon,maxLat'"
 // @Success 200 {object} []project.Project
 // @Router /v2.9/projects [GET]
 func getProjects_v2_9(r *http.Request, context *Context) *ApiResponse {
-	projects, err := context.ProjectService.GetProjects(context.Token.UID)
+	var projects []*project.Project
+	var err error
+
+	if value := r.URL.Query().Get("bbox"); value != "" {
+		var bbox *geometry.BoundingBox
+		bbox, err = geometry.ParseBoundingBox(value)
+		if err != nil {
+			return BadRequestError(errors.Wrap(err, "invalid bbox"))
+		}
+
+		projects, err = context.ProjectService.GetProjectsInBoundingBox(context.Token.UID, bbox)
+	} else {
+		projects, err = context.ProjectService.GetProjects(context.Token.UID)
+	}
 	if err != nil {
 		return InternalServerError(err)
 	}
@@ -73,6 +114,53 @@ func getProjects_v2_9(r *http.Request, context *Context) *ApiResponse {
 	return JsonResponse(projects)
 }
 
+// Get project summaries
+// @Summary Get summaries of the projects of the requesting user.
+// @Description Gets one page of summaries of all projects of the requesting user. A summary contains the key figures of a project (e.g. number of tasks and process points) but no tasks, members and comments.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param sort query string false "Attribute to sort by: 'id' (default), 'name', 'creationDate', 'lastActivity' or 'progress'"
+// @Param order query string false "Sort order: 'asc' (default) or 'desc'"
+// @Param offset query int false "Number of projects to skip (default 0)"
+// @Param limit query int false "Maximum number of projects on the page (default 50, maximum 500)"
+// @Success 200 {object} project.SummaryPage
+// @Router /v2.9/projects/summaries [GET]
+func getProjectSummaries_v2_9(r *http.Request, context *Context) *ApiResponse {
+	sort := project.SortById
+	if value := r.URL.Query().Get("sort"); value != "" {
+		sort = project.SummarySort(value)
+	}
+
+	descending := false
+	switch order := r.URL.Query().Get("order"); order {
+	case "", "asc":
+	case "desc":
+		descending = true
+	default:
+		return BadRequestError(errors.New(fmt.Sprintf("unknown order '%s'", order)))
+	}
+
+	offset, err := util.GetOptionalIntParam("offset", r, 0)
+	if err != nil {
+		return BadRequestError(err)
+	}
+
+	limit, err := util.GetOptionalIntParam("limit", r, project.DefaultSummaryLimit)
+	if err != nil {
+		return BadRequestError(err)
+	}
+
+	page, err := context.ProjectService.GetSummaries(context.Token.UID, sort, descending, offset, limit)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	context.Log("Successfully got %d project summaries", len(page.Summaries))
+
+	return JsonResponse(page)
+}
+
 // Add projects
 // @Summary Adds a new project.
 // @Version 2.9
@@ -95,7 +183,7 @@ func addProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 
 	addedProject, err := context.ProjectService.AddProjectWithTasks(&dto.Project, dto.Tasks)
 	if err != nil {
-		return InternalServerError(errors.Wrap(err, "error adding project with tasks"))
+		return ServiceError(errors.Wrap(err, "error adding project with tasks"))
 	}
 
 	sendAdd_v2_9(context.WebsocketSender, addedProject)
@@ -105,6 +193,131 @@ func addProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 	return JsonResponse(addedProject)
 }
 
+// Get tasks of project
+// @Summary Gets one page of tasks of a project.
+// @Description Gets one page of tasks of the project, which match all given filters. The next page can be requested with the 'nextCursor' of the returned page and the same filters and sort order. The requesting user must be a member of the project.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param id path string true "ID of the project"
+// @Param bbox query string false "Only tasks intersecting this bounding box of the form 'minLon,minLat,maxLon,maxLat'"
+// @Param assignedUser query string false "Only tasks assigned to this user"
+// @Param unassigned query bool false "Only tasks without assigned user"
+// @Param state query string false "Only tasks in this state: 'notStarted', 'inProgress' or 'done'"
+// @Param hasComments query bool false "Only tasks with comments"
+// @Param sort query string false "Attribute to sort by: 'id' (default), 'name', 'progress' or 'assignedUser'"
+// @Param order query string false "Sort order: 'asc' (default) or 'desc'"
+// @Param cursor query string false "The 'nextCursor' of the previous page"
+// @Param limit query int false "Maximum number of tasks on the page (default 100, maximum 1000)"
+// @Success 200 {object} task.Page
+// @Router /v2.9/projects/{id}/tasks [GET]
+func getProjectTasks_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	query := r.URL.Query()
+	listDto := &task.ListDto{
+		AssignedUser: query.Get("assignedUser"),
+		State:        task.ProgressState(query.Get("state")),
+		Sort:         task.SortById,
+		Cursor:       query.Get("cursor"),
+	}
+
+	if value := query.Get("bbox"); value != "" {
+		bbox, err := geometry.ParseBoundingBox(value)
+		if err != nil {
+			return BadRequestError(errors.Wrap(err, "invalid bbox"))
+		}
+		listDto.BoundingBox = bbox
+	}
+
+	if value := query.Get("unassigned"); value != "" {
+		unassigned, err := strconv.ParseBool(value)
+		if err != nil {
+			return BadRequestError(errors.Wrap(err, "url param 'unassigned' is not a boolean"))
+		}
+		listDto.Unassigned = unassigned
+	}
+
+	if value := query.Get("hasComments"); value != "" {
+		hasComments, err := strconv.ParseBool(value)
+		if err != nil {
+			return BadRequestError(errors.Wrap(err, "url param 'hasComments' is not a boolean"))
+		}
+		listDto.HasComments = hasComments
+	}
+
+	if value := query.Get("sort"); value != "" {
+		listDto.Sort = task.TaskSort(value)
+	}
+
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		listDto.Descending = true
+	default:
+		return BadRequestError(errors.New(fmt.Sprintf("unknown order '%s'", order)))
+	}
+
+	limit, err := util.GetOptionalIntParam("limit", r, task.DefaultPageLimit)
+	if err != nil {
+		return BadRequestError(err)
+	}
+	listDto.Limit = limit
+
+	page, err := context.TaskService.GetTasksOfProject(projectId, listDto, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	context.Log("Successfully got %d tasks of project %s", len(page.Tasks), projectId)
+
+	return JsonResponse(page)
+}
+
+// Add tasks to project
+// @Summary Adds new tasks to an existing project.
+// @Description Adds the given tasks to the project, e.g. to extend the area of the project. The requesting user must be the owner of the project. The project must not exceed the maximum amount of tasks per project afterwards.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param id path string true "ID of the project"
+// @Param tasks body []task.DraftDto true "Draft tasks to add"
+// @Success 200 {object} project.Project
+// @Router /v2.9/projects/{id}/tasks [POST]
+func addTasks_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error reading request body"))
+	}
+
+	var drafts []task.DraftDto
+	err = json.Unmarshal(bodyBytes, &drafts)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error unmarshalling task drafts"))
+	}
+
+	updatedProject, err := context.ProjectService.AddTasks(projectId, drafts, context.Token.UID)
+	if err != nil {
+		return ServiceError(errors.Wrapf(err, "error adding tasks to project %s", projectId))
+	}
+
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully added %d tasks to project %s", len(drafts), projectId)
+
+	return JsonResponse(updatedProject)
+}
+
 // Get project
 // @Summary Get a specific project.
 // @Description Gets a specific project. The requesting user must be a member of the project.
@@ -159,7 +372,7 @@ func leaveProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 
 // Remove user
 // @Summary Remove a user from a project.
-// @Description Removes a user from the project. The requesting user must be the owner of the project and cannot be removed.
+// @Description Removes a user from the project. The requesting user must be the owner or a moderator of the project. Only the owner can remove moderators and the owner cannot be removed.
 // @Version 2.9
 // @Tags projects
 // @Produce json
@@ -191,6 +404,146 @@ func removeUser_v2_9(r *http.Request, context *Context) *ApiResponse {
 	return JsonResponse(updatedProject)
 }
 
+// Transfer ownership
+// @Summary Makes another member the owner of the project.
+// @Description Transfers the ownership of the project to the given member. The requesting user must be the owner of the project and becomes a moderator.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param id path string true "ID of the project"
+// @Param uid query string true "The OSM user-ID of the new owner"
+// @Success 200 {object} project.Project
+// @Router /v2.9/projects/{id}/owner [PUT]
+func transferOwnership_v2_9(r *http.Request, context *Context) *ApiResponse {
+	newOwner, err := util.GetParam("uid", r)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "url param 'uid' not set"))
+	}
+
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	updatedProject, err := context.ProjectService.TransferOwnership(projectId, newOwner, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully transferred ownership of project %s to user '%s'", projectId, newOwner)
+
+	return JsonResponse(updatedProject)
+}
+
+// Set boundary
+// @Summary Sets the boundary of the project.
+// @Description Sets the boundary of the project, which is the whole area to map. The boundary must be a GeoJSON polygon or multi-polygon (geometry or feature) and is repaired if necessary (e.g. the winding order). The requesting user must be the owner or a moderator of the project.
+// @Version 2.9
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the project"
+// @Param boundary body string true "GeoJSON polygon or multi-polygon (geometry or feature)"
+// @Success 200 {object} project.Project
+// @Router /v2.9/projects/{id}/boundary [PUT]
+func setProjectBoundary_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error reading request body"))
+	}
+	if len(bodyBytes) == 0 {
+		return BadRequestError(errors.New("no boundary given"))
+	}
+
+	updatedProject, err := context.ProjectService.SetBoundary(projectId, string(bodyBytes), context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully set boundary of project %s", projectId)
+
+	return JsonResponse(updatedProject)
+}
+
+// Remove boundary
+// @Summary Removes the boundary of the project.
+// @Description Removes the boundary of the project. The requesting user must be the owner or a moderator of the project.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param id path string true "ID of the project"
+// @Success 200 {object} project.Project
+// @Router /v2.9/projects/{id}/boundary [DELETE]
+func removeProjectBoundary_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	updatedProject, err := context.ProjectService.SetBoundary(projectId, "", context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully removed boundary of project %s", projectId)
+
+	return JsonResponse(updatedProject)
+}
+
+// Set role of user
+// @Summary Sets the role of a user within the project.
+// @Description Sets the role of a member of the project. Possible roles are MODERATOR, MEMBER and VIEWER. The requesting user must be the owner of the project. The role of the owner can't be changed.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param id path string true "ID of the project"
+// @Param uid path string true "OSM user-Id of the member whose role should be changed"
+// @Param role query string true "The new role of the user"
+// @Success 200 {object} project.Project
+// @Router /v2.9/projects/{id}/users/{uid}/role [PUT]
+func setUserRole_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	userId, ok := vars["uid"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'uid' not set"))
+	}
+
+	role, err := util.GetParam("role", r)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "url param 'role' not set"))
+	}
+
+	updatedProject, err := context.ProjectService.SetRole(projectId, userId, permission.Role(role), context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully set role of user '%s' in project %s to %s", userId, projectId, role)
+
+	return JsonResponse(updatedProject)
+}
+
 // Add a new comment to the given project.
 // @Summary Add a new comment to the given project.
 // @Description Add a new comment to the given project. The number of maximum characters is restricted by the server config.
@@ -269,14 +622,84 @@ func deleteProjects_v2_9(r *http.Request, context *Context) *ApiResponse {
 	return EmptyResponse()
 }
 
+// Get project statistics
+// @Summary Gets statistics about the progress of the project.
+// @Description Gets the number of tasks per state, the contributions of each user and the process point changes over time. The requesting user must be a member of the project.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param id path string true "ID of the project"
+// @Param interval query string false "Interval the process point changes are grouped by. One of 'day' (default), 'week' and 'month'."
+// @Success 200 {object} project.Statistics
+// @Router /v2.9/projects/{id}/statistics [GET]
+func getProjectStatistics_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	interval := task.IntervalDay
+	if value := r.URL.Query().Get("interval"); value != "" {
+		interval = task.ProgressInterval(value)
+	}
+
+	statistics, err := context.ProjectService.GetStatistics(projectId, interval, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	context.Log("Successfully got statistics of project %s", projectId)
+
+	return JsonResponse(statistics)
+}
+
+// Check coverage of project
+// @Summary Finds overlapping tasks and gaps between tasks.
+// @Description Determines the areas where tasks of the project overlap each other and the areas not covered by any task. Gaps are searched within the given boundary, the boundary of the project or, without any boundary, within the convex hull of all tasks. The result is a GeoJSON feature collection with one feature per problem. Each feature has the properties 'type' ('overlap' or 'gap'), 'area' (in square meters) and, for overlaps, the 'taskIds' of both tasks. The requesting user must be a member of the project.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param id path string true "ID of the project"
+// @Param boundary query string false "GeoJSON polygon or multi-polygon (geometry or feature) within which gaps are searched"
+// @Success 200 {object} geojson.FeatureCollection
+// @Router /v2.9/projects/{id}/coverage-check [GET]
+func checkProjectCoverage_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	var boundary *geojson.Geometry
+	if value := r.URL.Query().Get("boundary"); value != "" {
+		var err error
+		boundary, err = geometry.ParseAreaGeometry(value)
+		if err != nil {
+			return BadRequestError(errors.Wrap(err, "invalid boundary"))
+		}
+	}
+
+	coverage, err := context.TaskService.CheckCoverage(projectId, boundary, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	context.Log("Successfully checked coverage of project %s", projectId)
+
+	return JsonResponse(coverage)
+}
+
 // Get a JSON representation of the project.
 // @Summary Get a JSON representation of the project.
-// @Description This aims to transfer a project to another STM instance or to simply create a backup of a project.
+// @Description This aims to transfer a project to another STM instance or to simply create a backup of a project. With the format 'geojson', the tasks are exported as GeoJSON feature collection, which can be used in other tools like QGIS or JOSM. With the format 'osm', the task boundaries are exported as OSM XML file for JOSM. The formats 'gpx' and 'kml' are meant for apps like OsmAnd or Google Earth.
 // @Version 2.9
 // @Tags projects
 // @Produce json
+// @Produce xml
 // @Param id path string true "ID of the project"
-// @Success 200 {object} project.Project
+// @Param format query string false "The export format. One of 'stm' (default), 'geojson', 'osm', 'gpx' and 'kml'."
+// @Success 200 {object} export.ProjectExport
 // @Router /v2.9/projects/{id}/export [GET]
 func exportProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 	vars := mux.Vars(r)
@@ -285,21 +708,57 @@ func exportProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 		return BadRequestError(errors.New("url segment 'id' not set"))
 	}
 
-	projectExport, err := context.ExportService.ExportProject(projectId, context.Token.UID)
-	if err != nil {
-		return InternalServerError(err)
+	format := export.StmFormat
+	if value := r.URL.Query().Get("format"); value != "" {
+		format = export.Format(value)
 	}
 
-	return JsonResponse(projectExport)
+	switch format {
+	case export.StmFormat:
+		projectExport, err := context.ExportService.ExportProject(projectId, context.Token.UID)
+		if err != nil {
+			return ServiceError(err)
+		}
+		return JsonResponse(projectExport)
+	case export.GeoJsonFormat:
+		featureCollection, err := context.ExportService.ExportProjectAsGeoJson(projectId, context.Token.UID)
+		if err != nil {
+			return ServiceError(err)
+		}
+		return JsonResponse(featureCollection)
+	case export.OsmFormat:
+		osmXml, err := context.ExportService.ExportProjectAsOsm(projectId, context.Token.UID)
+		if err != nil {
+			return ServiceError(err)
+		}
+		return FileResponse(osmXml, "application/xml")
+	case export.GpxFormat:
+		gpx, err := context.ExportService.ExportProjectAsGpx(projectId, context.Token.UID)
+		if err != nil {
+			return ServiceError(err)
+		}
+		return FileResponse(gpx, "application/gpx+xml")
+	case export.KmlFormat:
+		kml, err := context.ExportService.ExportProjectAsKml(projectId, context.Token.UID)
+		if err != nil {
+			return ServiceError(err)
+		}
+		return FileResponse(kml, "application/vnd.google-earth.kml+xml")
+	}
+
+	return BadRequestError(errors.New(fmt.Sprintf("unknown export format '%s'", format)))
 }
 
 // Imports a previously exported project.
 // @Summary Imports a previously exported project.
-// @Description This aims to import a project from e.g. a backup or to migrate to another STM instance.
+// @Description This aims to import a project from e.g. a backup or to migrate to another STM instance. Alternatively, a GeoJSON feature collection (e.g. from QGIS) can be imported. Each feature becomes a task and the properties 'name', 'max_process_points' and 'process_points' are used for the tasks. With the format 'hottm', the task grid of the HOT Tasking Manager can be imported and the task states are turned into process points.
 // @Version 2.9
 // @Tags projects
 // @Produce json
-// @Param projectExport body export.ProjectExport true "The project to import"
+// @Param projectExport body export.ProjectExport true "The project to import or a GeoJSON feature collection"
+// @Param name query string false "The name of the new project. Only used and required for GeoJSON feature collections."
+// @Param format query string false "Set to 'hottm' when importing the task grid of the HOT Tasking Manager. Otherwise the format is detected automatically."
+// @Success 200 {object} project.Project
 // @Router /v2.9/projects/import [POST]
 func importProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 	bodyBytes, err := io.ReadAll(r.Body)
@@ -307,6 +766,19 @@ func importProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 		return BadRequestError(errors.Wrap(err, "error reading request body"))
 	}
 
+	var typeDto struct {
+		Type string `json:"type"`
+	}
+	err = json.Unmarshal(bodyBytes, &typeDto)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error unmarshalling request body"))
+	}
+
+	if typeDto.Type == "FeatureCollection" {
+		format := export.Format(r.URL.Query().Get("format"))
+		return importFeatureCollection_v2_9(r, bodyBytes, format, context)
+	}
+
 	var dto export.ProjectExport
 	err = json.Unmarshal(bodyBytes, &dto)
 	if err != nil {
@@ -315,7 +787,7 @@ func importProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 
 	addedProject, err := context.ExportService.ImportProject(&dto, context.Token.UID)
 	if err != nil {
-		return InternalServerError(errors.Wrap(err, "error importing project with tasks"))
+		return ServiceError(errors.Wrap(err, "error importing project with tasks"))
 	}
 
 	sendAdd_v2_9(context.WebsocketSender, addedProject)
@@ -325,6 +797,37 @@ func importProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 	return JsonResponse(addedProject)
 }
 
+func importFeatureCollection_v2_9(r *http.Request, bodyBytes []byte, format export.Format, context *Context) *ApiResponse {
+	projectName, err := util.GetParam("name", r)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "url param 'name' not set"))
+	}
+
+	featureCollection, err := geojson.UnmarshalFeatureCollection(bodyBytes)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error unmarshalling feature collection"))
+	}
+
+	var addedProject *project.Project
+	switch format {
+	case export.HotTmFormat:
+		addedProject, err = context.ExportService.ImportHotTmProject(featureCollection, projectName, context.Token.UID)
+	case "", export.GeoJsonFormat:
+		addedProject, err = context.ExportService.ImportFeatureCollection(featureCollection, projectName, context.Token.UID)
+	default:
+		return BadRequestError(errors.New(fmt.Sprintf("unknown import format '%s'", format)))
+	}
+	if err != nil {
+		return ServiceError(errors.Wrap(err, "error importing feature collection"))
+	}
+
+	sendAdd_v2_9(context.WebsocketSender, addedProject)
+
+	context.Log("Successfully imported project %s with %d tasks from feature collection", addedProject.Id, len(featureCollection.Features))
+
+	return JsonResponse(addedProject)
+}
+
 // Update project name, description and JOSM data source.
 // @Summary Update project name, description and JOSM data source.
 // @Description Updates the projects name/title, description and the JOSM data source. The requesting user must be the owner of the project.
@@ -353,7 +856,7 @@ func updateProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 		return InternalServerError(errors.Wrap(err, "error unmarshalling project update"))
 	}
 
-	updatedProject, err := context.ProjectService.Update(projectId, dto.Name, dto.Description, dto.JosmDataSource, context.Token.UID)
+	updatedProject, err := context.ProjectService.Update(projectId, dto.Name, dto.Description, dto.JosmDataSource, dto.AssignmentTimeout, dto.NeedsValidation, dto.Public, context.Token.UID)
 	if err != nil {
 		return InternalServerError(err)
 	}
@@ -367,7 +870,7 @@ func updateProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 
 // Add user
 // @Summary Adds a user to the project
-// @Description Adds the given user to the project. The requesting user must be the owner of the project.
+// @Description Adds the given user as member to the project. The requesting user must be the owner or a moderator of the project.
 // @Version 2.9
 // @Tags projects
 // @Produce json
@@ -389,7 +892,7 @@ func addUserToProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 
 	updatedProject, err := context.ProjectService.AddUser(projectId, userToAdd, context.Token.UID)
 	if err != nil {
-		return InternalServerError(err)
+		return ServiceError(err)
 	}
 
 	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
@@ -399,6 +902,201 @@ func addUserToProject_v2_9(r *http.Request, context *Context) *ApiResponse {
 	return JsonResponse(updatedProject)
 }
 
+// Join project
+// @Summary Adds the requesting user to the project.
+// @Description Adds the requesting user as member to the project. This only works for public projects.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param id path string true "ID of the project to join"
+// @Success 200 {object} project.Project
+// @Router /v2.9/projects/{id}/join [POST]
+func joinProject_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	updatedProject, err := context.ProjectService.JoinProject(projectId, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully added user '%s' to public project %s", context.Token.UID, projectId)
+
+	return JsonResponse(updatedProject)
+}
+
+// Create invitation
+// @Summary Creates an invitation token for the project.
+// @Description Creates a signed token, which can be used by any user to join the project until it expires. The requesting user must be the owner or a moderator of the project.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param id path string true "ID of the project"
+// @Success 200 {object} project.InvitationDto
+// @Router /v2.9/projects/{id}/invitations [POST]
+func createInvitation_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	projectId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	invitation, err := context.ProjectService.CreateInvitation(projectId, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	context.Log("Successfully created invitation for project %s", projectId)
+
+	return JsonResponse(invitation)
+}
+
+// Redeem invitation
+// @Summary Adds the requesting user to the project of the invitation.
+// @Description Adds the requesting user as member to the project the given invitation token has been created for.
+// @Version 2.9
+// @Tags projects
+// @Produce json
+// @Param token query string true "The invitation token"
+// @Success 200 {object} project.Project
+// @Router /v2.9/invitations [POST]
+func redeemInvitation_v2_9(r *http.Request, context *Context) *ApiResponse {
+	token, err := util.GetParam("token", r)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "url param 'token' not set"))
+	}
+
+	updatedProject, err := context.ProjectService.RedeemInvitation(token, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully added user '%s' to project %s via invitation", context.Token.UID, updatedProject.Id)
+
+	return JsonResponse(updatedProject)
+}
+
+// Split an area into task drafts
+// @Summary Divides an area into task drafts.
+// @Description Divides the given polygon or multi-polygon into task drafts using a square, hexagon or triangle grid or voronoi cells. Nothing is stored, the drafts can be used to create a new project.
+// @Version 2.9
+// @Tags tasks
+// @Produce json
+// @Param split body task.SplitDto true "The area to divide and the division parameters"
+// @Success 200 {object} []task.DraftDto
+// @Router /v2.9/tasks/split [POST]
+func splitArea_v2_9(r *http.Request, context *Context) *ApiResponse {
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error reading request body"))
+	}
+
+	var dto task.SplitDto
+	err = json.Unmarshal(bodyBytes, &dto)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error unmarshalling split parameters"))
+	}
+
+	taskDrafts, err := context.TaskService.SplitArea(&dto)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error splitting area"))
+	}
+
+	context.Log("Successfully split area into %d task drafts", len(taskDrafts))
+
+	return JsonResponse(taskDrafts)
+}
+
+// Split a task
+// @Summary Splits an existing task into several new tasks.
+// @Description Divides the task into new tasks using a square, hexagon or triangle grid or voronoi cells. The new tasks replace the original task and get its process points, validation state and comments. The requesting user must be the owner or a moderator of the project.
+// @Version 2.9
+// @Tags tasks
+// @Produce json
+// @Param id path string true "The ID of the task"
+// @Param split body task.TaskSplitDto true "The division parameters"
+// @Success 200 {object} []task.Task
+// @Router /v2.9/tasks/{id}/split [POST]
+func splitTask_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	taskId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error reading request body"))
+	}
+
+	var dto task.TaskSplitDto
+	err = json.Unmarshal(bodyBytes, &dto)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error unmarshalling split parameters"))
+	}
+
+	newTasks, err := context.TaskService.SplitTask(taskId, &dto, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	// All members need the whole project, the original task is gone and there are several new tasks
+	updatedProject, err := context.ProjectService.GetProjectByTask(newTasks[0].Id)
+	if err != nil {
+		return InternalServerError(err)
+	}
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully split task '%s' into %d tasks", taskId, len(newTasks))
+
+	return JsonResponse(newTasks)
+}
+
+// Merge tasks
+// @Summary Merges several tasks into one task.
+// @Description Replaces the given tasks by one task with a multi-polygon geometry, the summed up process points and all comments. All tasks must belong to the same project and must not be assigned to different users. The requesting user must be the owner or a moderator of the project.
+// @Version 2.9
+// @Tags tasks
+// @Produce json
+// @Param merge body task.MergeDto true "The IDs of the tasks to merge"
+// @Success 200 {object} task.Task
+// @Router /v2.9/tasks/merge [POST]
+func mergeTasks_v2_9(r *http.Request, context *Context) *ApiResponse {
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error reading request body"))
+	}
+
+	var dto task.MergeDto
+	err = json.Unmarshal(bodyBytes, &dto)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error unmarshalling task IDs"))
+	}
+
+	mergedTask, err := context.TaskService.MergeTasks(dto.TaskIds, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	// All members need the whole project, the merged tasks are gone
+	updatedProject, err := context.ProjectService.GetProjectByTask(mergedTask.Id)
+	if err != nil {
+		return InternalServerError(err)
+	}
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully merged tasks %v into task '%s'", dto.TaskIds, mergedTask.Id)
+
+	return JsonResponse(*mergedTask)
+}
+
 // Get a task
 // @Summary Gets the task with the given id.
 // @Description Gets the task with the given id.
@@ -423,6 +1121,113 @@ func getTask_v2_9(r *http.Request, context *Context) *ApiResponse {
 	return JsonResponse(*task)
 }
 
+// Update a task
+// @Summary Updates the task with the given id.
+// @Description Changes the name, the maximum process points and optionally the geometry of the task. Process points above the new maximum are lowered to the new maximum. A task finished this way needs to be reviewed, when the project needs validation. The requesting user must be the owner or a moderator of the project.
+// @Version 2.9
+// @Tags tasks
+// @Produce json
+// @Param id path string true "The ID of the task"
+// @Param task body task.UpdateDto true "The new properties of the task"
+// @Success 200 {object} task.Task
+// @Router /v2.9/tasks/{id} [PUT]
+func updateTask_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	taskId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error reading request body"))
+	}
+
+	var dto task.UpdateDto
+	err = json.Unmarshal(bodyBytes, &dto)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error unmarshalling task update"))
+	}
+
+	updatedTask, err := context.TaskService.UpdateTask(taskId, &dto, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	err = sendTaskUpdate_v2_9(context.WebsocketSender, updatedTask, context)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	context.Log("Successfully updated task %s", taskId)
+
+	return JsonResponse(*updatedTask)
+}
+
+// Delete a task
+// @Summary Deletes the task with the given id.
+// @Description Removes the task and its comments from its project. The requesting user must be the owner or a moderator of the project. The last task of a project can't be deleted.
+// @Version 2.9
+// @Tags tasks
+// @Param id path string true "The ID of the task"
+// @Router /v2.9/tasks/{id} [DELETE]
+func deleteTask_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	taskId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	// The project has to be determined before the task is gone
+	projectOfTask, err := context.ProjectService.GetProjectByTask(taskId)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	err = context.TaskService.Delete([]string{taskId}, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	// Get the project again, so that the update doesn't contain the deleted task anymore
+	updatedProject, err := context.ProjectService.GetProject(projectOfTask.Id, context.Token.UID)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	sendUpdate_v2_9(context.WebsocketSender, updatedProject)
+
+	context.Log("Successfully deleted task %s", taskId)
+
+	return EmptyResponse()
+}
+
+// Get task history
+// @Summary Gets the history of the task with the given id.
+// @Description Gets all assignments, unassignments and process point changes of the task from oldest to newest. The requesting user must be a member of the project.
+// @Version 2.9
+// @Tags tasks
+// @Produce json
+// @Param id path string true "The ID of the task"
+// @Success 200 {object} []task.Event
+// @Router /v2.9/tasks/{id}/history [GET]
+func getTaskHistory_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	taskId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	events, err := context.TaskService.GetHistory(taskId, context.Token.UID)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	context.Log("Successfully got history of task %s", taskId)
+
+	return JsonResponse(events)
+}
+
 // Assign user
 // @Summary Assigns a user to a task
 // @Description Assigns the requesting user to the given task. The requesting user must be a member of the project.
@@ -515,7 +1320,7 @@ func setProcessPoints_v2_9(r *http.Request, context *Context) *ApiResponse {
 
 	task, err := context.TaskService.SetProcessPoints(taskId, processPoints, context.Token.UID)
 	if err != nil {
-		return InternalServerError(err)
+		return ServiceError(err)
 	}
 
 	// Send via websockets
@@ -529,6 +1334,80 @@ func setProcessPoints_v2_9(r *http.Request, context *Context) *ApiResponse {
 	return JsonResponse(*task)
 }
 
+// Validate task
+// @Summary Marks the task as validated.
+// @Description Marks the task, which needs to be reviewed, as validated. The project must need validation. The requesting user must be a member of the project and must not be the mapper of the task.
+// @Version 2.9
+// @Tags tasks
+// @Produce json
+// @Param id path string true "The ID of the task"
+// @Success 200 {object} task.Task
+// @Router /v2.9/tasks/{id}/validate [POST]
+func validateTask_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	taskId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	task, err := context.TaskService.ValidateTask(taskId, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	err = sendTaskUpdate_v2_9(context.WebsocketSender, task, context)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	context.Log("Successfully validated task '%s'", taskId)
+
+	return JsonResponse(*task)
+}
+
+// Reject task
+// @Summary Sends the task back to mapping.
+// @Description Sends the task, which needs to be reviewed, back to mapping. The given comment explains the rejection and is added to the task. The process points of the task are reset. The project must need validation. The requesting user must be a member of the project and must not be the mapper of the task.
+// @Version 2.9
+// @Tags tasks
+// @Produce json
+// @Param id path string true "The ID of the task"
+// @Param comment body comment.DraftDto true "The comment explaining the rejection"
+// @Success 200 {object} task.Task
+// @Router /v2.9/tasks/{id}/reject [POST]
+func rejectTask_v2_9(r *http.Request, context *Context) *ApiResponse {
+	vars := mux.Vars(r)
+	taskId, ok := vars["id"]
+	if !ok {
+		return BadRequestError(errors.New("url segment 'id' not set"))
+	}
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error reading request body"))
+	}
+
+	var dto comment.DraftDto
+	err = json.Unmarshal(bodyBytes, &dto)
+	if err != nil {
+		return BadRequestError(errors.Wrap(err, "error unmarshalling comment draft"))
+	}
+
+	task, err := context.TaskService.RejectTask(taskId, &dto, context.Token.UID)
+	if err != nil {
+		return ServiceError(err)
+	}
+
+	err = sendTaskUpdate_v2_9(context.WebsocketSender, task, context)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	context.Log("Successfully sent task '%s' back to mapping", taskId)
+
+	return JsonResponse(*task)
+}
+
 // Add a new comment to the given task.
 // @Summary Add a new comment to the given task.
 // @Description Add a new comment to the given task. The number of maximum characters is restricted by the server config.
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+	"github.com/hauke96/sigolo"
+	"github.com/pkg/errors"
+	"runtime/debug"
+	"stm/config"
+	"stm/util"
+	"time"
+)
+
+// startAssignmentSweeper periodically removes the assigned users from tasks whose assignment expired. This runs in the
+// background for the whole lifetime of the server.
+func startAssignmentSweeper() error {
+	interval, err := time.ParseDuration(config.Conf.AssignmentSweepInterval)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse assignment sweep interval '%s'", config.Conf.AssignmentSweepInterval)
+	}
+	if interval <= 0 {
+		return errors.New(fmt.Sprintf("assignment sweep interval must be positive (%s)", interval))
+	}
+
+	sigolo.Info("Check for expired assignments every %s", interval)
+
+	go func() {
+		for range time.Tick(interval) {
+			sweepExpiredAssignments()
+		}
+	}()
+
+	return nil
+}
+
+// sweepExpiredAssignments unassigns all expired tasks within one transaction and notifies the project members about the
+// changed tasks.
+func sweepExpiredAssignments() {
+	logger := util.NewLogger()
+
+	// There's no user (and therefore no token) causing the sweep
+	context, err := createContext(nil, logger)
+	if err != nil {
+		logger.Err("Unable to create context for sweeping expired assignments: %s", err)
+		logger.Stack(err)
+		return
+	}
+
+	// Recover from panic and perform rollback on transaction. A panic must not kill the server.
+	defer func() {
+		if r := recover(); r != nil {
+			context.Err("!! PANIC !! Recover from panic while sweeping expired assignments: %v", r)
+			context.Log("%s", debug.Stack())
+
+			rollbackErr := context.Transaction.Rollback()
+			if rollbackErr != nil {
+				logger.Stack(errors.Wrap(rollbackErr, "error performing rollback"))
+			}
+		}
+	}()
+
+	tasks, err := context.TaskService.UnassignExpiredTasks()
+	if err != nil {
+		panic(err)
+	}
+
+	for _, t := range tasks {
+		err = sendTaskUpdate_v2_9(context.WebsocketSender, t, context)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	err = context.Transaction.Commit()
+	if err != nil {
+		panic(errors.Wrap(err, "unable to commit transaction"))
+	}
+
+	if len(tasks) != 0 {
+		context.Log("Unassigned users from %d tasks with expired assignment", len(tasks))
+	}
+}
@@ -43,7 +43,7 @@ func createContext(token *oauth2.Token, logger *util.Logger) (*Context, error) {
 
 	ctx.TaskService = task.Init(tx, ctx.Logger, permissionStore, commentService, commentStore)
 	ctx.ProjectService = project.Init(tx, ctx.Logger, ctx.TaskService, permissionStore, commentService, commentStore)
-	ctx.ExportService = export.Init(logger, ctx.ProjectService)
+	ctx.ExportService = export.Init(logger, ctx.ProjectService, ctx.TaskService)
 	ctx.WebsocketSender = websocket.Init(ctx.Logger)
 
 	return ctx, nil
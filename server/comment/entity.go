@@ -2,6 +2,9 @@ package comment
 
 import "time"
 
+// SystemAuthorId is the author of comments created automatically by the server itself.
+const SystemAuthorId = "stm"
+
 type Comment struct {
 	Id           string     `json:"id"`           // The ID of the task.
 	Text         string     `json:"text"`         // The name of the task. If the properties of the geometry feature contain the field "name", this field is used here. If no name has been set, this field will be empty.
@@ -29,3 +29,20 @@ func (s *Service) AddComment(listId string, commentDraft *DraftDto, authorId str
 
 	return s.store.addComment(listId, commentDraft.Text, authorId, time.Now().UTC())
 }
+
+// ImportComments adds the comments to the list while keeping their authors and creation dates. This is used to
+// restore comments from an export.
+func (s *Service) ImportComments(listId string, comments []Comment) error {
+	for _, c := range comments {
+		if c.CreationDate == nil {
+			return errors.New(fmt.Sprintf("creation date of comment from %s missing", c.AuthorId))
+		}
+
+		err := s.store.addComment(listId, c.Text, c.AuthorId, c.CreationDate.UTC())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
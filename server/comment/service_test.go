@@ -2,12 +2,14 @@ package comment
 
 import (
 	"database/sql"
+	"fmt"
 	"stm/config"
 	"stm/test"
 	"stm/util"
 	"testing"
 
 	"github.com/hauke96/sigolo"
+	"github.com/pkg/errors"
 )
 
 var (
@@ -47,3 +49,24 @@ func TestAddCommentWithUnicodeCharacters(t *testing.T) {
 		return err
 	})
 }
+
+func TestGetCommentsOfLists(t *testing.T) {
+	h.Run(t, func() error {
+		comments, err := s.store.GetCommentsOfLists([]string{"1", "2", "3"})
+		if err != nil {
+			return err
+		}
+
+		if len(comments) != 3 {
+			return errors.New(fmt.Sprintf("Expected comments of 3 lists but got %d", len(comments)))
+		}
+		if comments["1"] == nil || len(comments["1"]) != 0 || comments["3"] == nil || len(comments["3"]) != 0 {
+			return errors.New(fmt.Sprintf("Expected existing but empty comment lists: %+v", comments))
+		}
+		if len(comments["2"]) != 2 || comments["2"][0].Id != "1" || comments["2"][1].Id != "2" {
+			return errors.New(fmt.Sprintf("Comments of list 2 do not match: %+v", comments["2"]))
+		}
+
+		return nil
+	})
+}
@@ -3,6 +3,7 @@ package comment
 import (
 	"database/sql"
 	"fmt"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"stm/util"
 	"strconv"
@@ -34,35 +35,45 @@ func GetStore(tx *sql.Tx, logger *util.Logger) *Store {
 }
 
 func (s *Store) GetComments(listId string) ([]Comment, error) {
-	rawQueryString := `
-SELECT comment.*
-FROM %s comment_list, %s comment
-WHERE
-	comment_list.id = $1 AND
-	comment_list.id = comment.comment_list_id;`
+	comments, err := s.GetCommentsOfLists([]string{listId})
+	if err != nil {
+		return nil, err
+	}
+
+	return comments[listId], nil
+}
+
+// GetCommentsOfLists loads the comments of all given lists with one single query. The result maps each list ID to the
+// comments of that list ordered by their ID. Lists without comments are mapped to an empty slice.
+func (s *Store) GetCommentsOfLists(listIds []string) (map[string][]Comment, error) {
+	result := make(map[string][]Comment, len(listIds))
+	for _, listId := range listIds {
+		result[listId] = make([]Comment, 0)
+	}
+
+	if len(listIds) == 0 {
+		return result, nil
+	}
 
-	query := fmt.Sprintf(rawQueryString, s.commentListTable, s.commentTable)
-	s.LogQuery(query, listId)
+	query := fmt.Sprintf("SELECT comment_list_id, id, text, creation_date, author_id FROM %s WHERE comment_list_id = ANY($1) ORDER BY id;", s.commentTable)
+	s.LogQuery(query, listIds)
 
-	rows, err := s.tx.Query(query, listId)
+	rows, err := s.tx.Query(query, pq.Array(listIds))
 	if err != nil {
 		return nil, errors.Wrap(err, "error executing query")
 	}
 	defer rows.Close()
 
-	comments := make([]Comment, 0)
 	for rows.Next() {
-		comment, err := rowToComment(rows)
+		listId, comment, err := rowToComment(rows)
 		if err != nil {
 			return nil, errors.Wrap(err, "error converting row into comment")
 		}
 
-		comments = append(comments, *comment)
+		result[listId] = append(result[listId], *comment)
 	}
 
-	rows.Close()
-
-	return comments, nil
+	return result, nil
 }
 
 func (s *Store) NewCommentList() (string, error) {
@@ -92,6 +103,45 @@ func (s *Store) NewCommentList() (string, error) {
 	return commentListId, nil
 }
 
+// DeleteCommentLists removes the given comment lists including all their comments.
+func (s *Store) DeleteCommentLists(listIds []string) error {
+	if len(listIds) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE comment_list_id = ANY($1)", s.commentTable)
+	s.LogQuery(query, listIds)
+
+	_, err := s.tx.Exec(query, pq.Array(listIds))
+	if err != nil {
+		return errors.Wrapf(err, "error deleting comments of lists %v", listIds)
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE id = ANY($1)", s.commentListTable)
+	s.LogQuery(query, listIds)
+
+	_, err = s.tx.Exec(query, pq.Array(listIds))
+	if err != nil {
+		return errors.Wrapf(err, "error deleting comment lists %v", listIds)
+	}
+
+	return nil
+}
+
+// CopyComments adds a copy of all comments of the source list to the target list. Author and creation date of the
+// comments stay the same.
+func (s *Store) CopyComments(sourceListId string, targetListId string) error {
+	query := fmt.Sprintf("INSERT INTO %s (comment_list_id, text, author_id, creation_date) SELECT $2, text, author_id, creation_date FROM %s WHERE comment_list_id = $1 ORDER BY creation_date", s.commentTable, s.commentTable)
+	s.LogQuery(query, sourceListId, targetListId)
+
+	_, err := s.tx.Exec(query, sourceListId, targetListId)
+	if err != nil {
+		return errors.Wrapf(err, "error copying comments from list %s to list %s", sourceListId, targetListId)
+	}
+
+	return nil
+}
+
 func (s *Store) addComment(listId string, text string, authorId string, creationDate time.Time) error {
 	query := fmt.Sprintf("INSERT INTO %s (comment_list_id, text, author_id, creation_date) VALUES($1, $2, $3, $4) RETURNING *", s.commentTable)
 	_, err := s.execQuery(query, listId, text, authorId, creationDate)
@@ -111,7 +161,7 @@ func (s *Store) execQuery(query string, params ...interface{}) (*Comment, error)
 		return nil, errors.New("there is no next row or an error happened")
 	}
 
-	t, err := rowToComment(rows)
+	_, t, err := rowToComment(rows)
 
 	if t == nil && err == nil {
 		return nil, errors.New(fmt.Sprintf("Task does not exist"))
@@ -120,12 +170,13 @@ func (s *Store) execQuery(query string, params ...interface{}) (*Comment, error)
 	return t, err
 }
 
-// rowToComment turns the current row into a Project object. This does not close the row.
-func rowToComment(rows *sql.Rows) (*Comment, error) {
+// rowToComment turns the current row into a Comment object and also returns the ID of its comment list. This does not
+// close the row.
+func rowToComment(rows *sql.Rows) (string, *Comment, error) {
 	var c commentRow
 	err := rows.Scan(&c.commentListId, &c.id, &c.text, &c.creationDate, &c.authorId)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not scan rows")
+		return "", nil, errors.Wrap(err, "could not scan rows")
 	}
 
 	result := Comment{}
@@ -139,5 +190,5 @@ func rowToComment(rows *sql.Rows) (*Comment, error) {
 		result.CreationDate = &t
 	}
 
-	return &result, nil
+	return strconv.Itoa(c.commentListId), &result, nil
 }
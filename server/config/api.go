@@ -5,6 +5,8 @@ type Dto struct {
 	MaxTasksPerProject   int    `json:"maxTasksPerProject"`   // Maximum amount of tasks allowed for a project.
 	MaxDescriptionLength int    `json:"maxDescriptionLength"` // Maximum length for the project description in characters. Default: 1000.
 	MaxCommentLength     int    `json:"maxCommentLength"`     // Maximum length for comments in characters. Default: 1000.
+	MinTaskArea          int    `json:"minTaskArea"`          // Minimum area of a task in square meters. Default: 0.
+	MaxTaskArea          int    `json:"maxTaskArea"`          // Maximum area of a task in square meters. 0 means there's no maximum. Default: 1000000000 (1000 km²).
 	TestEnvironment      bool   `json:"testEnvironment"`      // True when the server runs in an test environment
 	OsmApiUrl            string `json:"osmApiUrl"`            // The base-URL to the OSM server.
 }
@@ -15,6 +17,8 @@ func GetConfigDto() *Dto {
 		MaxTasksPerProject:   Conf.MaxTasksPerProject,
 		MaxDescriptionLength: Conf.MaxDescriptionLength,
 		MaxCommentLength:     Conf.MaxCommentLength,
+		MinTaskArea:          Conf.MinTaskArea,
+		MaxTaskArea:          Conf.MaxTaskArea,
 		TestEnvironment:      Conf.TestEnvironment,
 		OsmApiUrl:            Conf.OsmApiUrl,
 	}
@@ -16,16 +16,21 @@ var (
 )
 
 const (
-	EnvVarServerUrl             = "STM_SERVER_URL"
-	EnvVarPort                  = "STM_PORT"
-	EnvVarClientAuthRedirectUrl = "STM_CLIENT_AUTH_REDIRECT_URL"
-	EnvVarOsmBaseUrl            = "STM_OSM_BASE_URL"
-	EnvVarOsmApiUrl             = "STM_OSM_API_URL"
-	EnvVarTokenValidityDuration = "STM_TOKEN_VALIDITY_DURATION"
-	EnvVarSourceRepoURL         = "STM_SOURCE_REPO_URL"
-	EnvVarMaxTasksPerProject    = "STM_MAX_TASKS_PER_PROJECT"
-	EnvVarMaxDescriptionLength  = "STM_MAX_DESCRIPTION_LENGTH"
-	EnvVarMaxCommentLength      = "STM_MAX_COMMENT_LENGTH"
+	EnvVarServerUrl               = "STM_SERVER_URL"
+	EnvVarPort                    = "STM_PORT"
+	EnvVarClientAuthRedirectUrl   = "STM_CLIENT_AUTH_REDIRECT_URL"
+	EnvVarOsmBaseUrl              = "STM_OSM_BASE_URL"
+	EnvVarOsmApiUrl               = "STM_OSM_API_URL"
+	EnvVarTokenValidityDuration   = "STM_TOKEN_VALIDITY_DURATION"
+	EnvVarSourceRepoURL           = "STM_SOURCE_REPO_URL"
+	EnvVarMaxTasksPerProject      = "STM_MAX_TASKS_PER_PROJECT"
+	EnvVarMaxDescriptionLength    = "STM_MAX_DESCRIPTION_LENGTH"
+	EnvVarMaxCommentLength        = "STM_MAX_COMMENT_LENGTH"
+	EnvVarAssignmentSweepInterval = "STM_ASSIGNMENT_SWEEP_INTERVAL"
+	EnvVarInvitationValidity      = "STM_INVITATION_VALIDITY"
+	EnvVarInvitationSecret        = "STM_INVITATION_SECRET"
+	EnvVarMinTaskArea             = "STM_MIN_TASK_AREA"
+	EnvVarMaxTaskArea             = "STM_MAX_TASK_AREA"
 
 	EnvVarSslCertFile = "STM_SSL_CERT_FILE"
 	EnvVarSslKeyFile  = "STM_SSL_KEY_FILE"
@@ -51,6 +56,10 @@ const (
 	DefaultMaxTaskPerProject       = 1000
 	DefaultMaxDescriptionLength    = 1000
 	DefaultMaxCommentLength        = 1000
+	DefaultAssignmentSweepInterval = "5m"
+	DefaultInvitationValidity      = "168h"
+	DefaultMinTaskArea             = 0
+	DefaultMaxTaskArea             = 1000000000
 
 	DefaultDbUsername = "stm"
 	DefaultDbPassword = "secret"
@@ -62,16 +71,21 @@ const (
 )
 
 type Config struct {
-	ServerUrl             string `json:"server-url"`
-	Port                  int    `json:"port"`
-	ClientAuthRedirectUrl string `json:"client-auth-redirect-url"`
-	OsmBaseUrl            string `json:"osm-base-url"`
-	OsmApiUrl             string `json:"osm-api-url"`
-	TokenValidityDuration string `json:"token-validity"`
-	SourceRepoURL         string `json:"source-repo-url"`
-	MaxTasksPerProject    int    `json:"max-task-per-project"`   // Maximum amount of tasks allowed for a project.
-	MaxDescriptionLength  int    `json:"max-description-length"` // Maximum length for the project description in characters.
-	MaxCommentLength      int    `json:"max-comment-length"`     // Maximum length for comments in characters.
+	ServerUrl               string `json:"server-url"`
+	Port                    int    `json:"port"`
+	ClientAuthRedirectUrl   string `json:"client-auth-redirect-url"`
+	OsmBaseUrl              string `json:"osm-base-url"`
+	OsmApiUrl               string `json:"osm-api-url"`
+	TokenValidityDuration   string `json:"token-validity"`
+	SourceRepoURL           string `json:"source-repo-url"`
+	MaxTasksPerProject      int    `json:"max-task-per-project"`      // Maximum amount of tasks allowed for a project.
+	MaxDescriptionLength    int    `json:"max-description-length"`    // Maximum length for the project description in characters.
+	MaxCommentLength        int    `json:"max-comment-length"`        // Maximum length for comments in characters.
+	AssignmentSweepInterval string `json:"assignment-sweep-interval"` // Time between two checks for expired task assignments.
+	InvitationValidity      string `json:"invitation-validity"`       // Duration an invitation to a project can be redeemed.
+	InvitationSecret        string `json:"invitation-secret"`         // Secret to sign invitations with. The OAuth2 secret is used when not set.
+	MinTaskArea             int    `json:"min-task-area"`             // Minimum area of a task in square meters.
+	MaxTaskArea             int    `json:"max-task-area"`             // Maximum area of a task in square meters. 0 means there's no maximum.
 
 	SslCertFile string `json:"ssl-cert-file"`
 	SslKeyFile  string `json:"ssl-key-file"`
@@ -114,6 +128,11 @@ func LoadConfig(file string) {
 	Conf.MaxTasksPerProject = getConfigEntryInt(EnvVarMaxTasksPerProject, Conf.MaxTasksPerProject)
 	Conf.MaxDescriptionLength = getConfigEntryInt(EnvVarMaxDescriptionLength, Conf.MaxDescriptionLength)
 	Conf.MaxCommentLength = getConfigEntryInt(EnvVarMaxCommentLength, Conf.MaxCommentLength)
+	Conf.AssignmentSweepInterval = getConfigEntry(EnvVarAssignmentSweepInterval, Conf.AssignmentSweepInterval)
+	Conf.InvitationValidity = getConfigEntry(EnvVarInvitationValidity, Conf.InvitationValidity)
+	Conf.InvitationSecret = getConfigEntry(EnvVarInvitationSecret, Conf.InvitationSecret)
+	Conf.MinTaskArea = getConfigEntryInt(EnvVarMinTaskArea, Conf.MinTaskArea)
+	Conf.MaxTaskArea = getConfigEntryInt(EnvVarMaxTaskArea, Conf.MaxTaskArea)
 
 	// SSL configs
 	Conf.SslCertFile = getConfigEntry(EnvVarSslCertFile, Conf.SslCertFile)
@@ -148,6 +167,10 @@ func initDefaultConfig() {
 	Conf.MaxTasksPerProject = DefaultMaxTaskPerProject
 	Conf.MaxDescriptionLength = DefaultMaxDescriptionLength
 	Conf.MaxCommentLength = DefaultMaxCommentLength
+	Conf.AssignmentSweepInterval = DefaultAssignmentSweepInterval
+	Conf.InvitationValidity = DefaultInvitationValidity
+	Conf.MinTaskArea = DefaultMinTaskArea
+	Conf.MaxTaskArea = DefaultMaxTaskArea
 
 	Conf.DbUsername = DefaultDbUsername
 	Conf.DbPassword = DefaultDbPassword
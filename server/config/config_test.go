@@ -54,6 +54,18 @@ func TestInitConfig(t *testing.T) {
 		if Conf.MaxDescriptionLength != DefaultMaxDescriptionLength {
 			return errors.New(fmt.Sprintf("Default value of 'MaxDescriptionLength' wrong: Wanted %d but was %d", DefaultMaxDescriptionLength, Conf.MaxDescriptionLength))
 		}
+		if Conf.AssignmentSweepInterval != DefaultAssignmentSweepInterval {
+			return errors.New(fmt.Sprintf("Default value of 'AssignmentSweepInterval' wrong: Wanted %s but was %s", DefaultAssignmentSweepInterval, Conf.AssignmentSweepInterval))
+		}
+		if Conf.InvitationValidity != DefaultInvitationValidity {
+			return errors.New(fmt.Sprintf("Default value of 'InvitationValidity' wrong: Wanted %s but was %s", DefaultInvitationValidity, Conf.InvitationValidity))
+		}
+		if Conf.MinTaskArea != DefaultMinTaskArea {
+			return errors.New(fmt.Sprintf("Default value of 'MinTaskArea' wrong: Wanted %d but was %d", DefaultMinTaskArea, Conf.MinTaskArea))
+		}
+		if Conf.MaxTaskArea != DefaultMaxTaskArea {
+			return errors.New(fmt.Sprintf("Default value of 'MaxTaskArea' wrong: Wanted %d but was %d", DefaultMaxTaskArea, Conf.MaxTaskArea))
+		}
 
 		if Conf.DbUsername != DefaultDbUsername {
 			return errors.New(fmt.Sprintf("Default value of 'DbUsername' wrong: Wanted %s but was %s", DefaultDbUsername, Conf.DbUsername))
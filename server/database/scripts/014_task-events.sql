@@ -0,0 +1,25 @@
+BEGIN TRANSACTION;
+
+-- The history of a task should survive when the task is replaced (e.g. by splitting or merging tasks), therefore the
+-- events reference the project and not the task itself.
+CREATE TABLE task_events
+(
+	id                      SERIAL PRIMARY KEY NOT NULL,
+	project_id              INT                NOT NULL,
+	task_id                 INT                NOT NULL,
+	type                    TEXT               NOT NULL,
+	user_id                 TEXT               NOT NULL,
+	assigned_user           TEXT               NOT NULL DEFAULT '',
+	previous_process_points INT                NOT NULL DEFAULT 0,
+	process_points          INT                NOT NULL DEFAULT 0,
+	creation_date           TIMESTAMP          NOT NULL
+);
+
+ALTER TABLE task_events ADD FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE;
+
+CREATE INDEX task_events_task_id_index ON task_events (task_id);
+CREATE INDEX task_events_project_id_index ON task_events (project_id);
+
+INSERT INTO db_versions VALUES ('014');
+
+END TRANSACTION;
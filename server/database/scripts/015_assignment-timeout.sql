@@ -0,0 +1,13 @@
+BEGIN TRANSACTION;
+
+-- Timeout in hours after which an assignment expires. 0 means that assignments never expire.
+ALTER TABLE projects ADD COLUMN assignment_timeout INT NOT NULL DEFAULT 0;
+
+ALTER TABLE tasks ADD COLUMN assignment_date TIMESTAMP;
+
+-- The real assignment date of existing assignments is unknown, so they start now
+UPDATE tasks SET assignment_date = NOW() WHERE assigned_user <> '';
+
+INSERT INTO db_versions VALUES ('015');
+
+END TRANSACTION;
@@ -0,0 +1,13 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE projects ADD COLUMN needs_validation BOOLEAN NOT NULL DEFAULT false;
+
+-- One of 'MAPPING', 'NEEDS_REVIEW' and 'VALIDATED'
+ALTER TABLE tasks ADD COLUMN validation_state TEXT NOT NULL DEFAULT 'MAPPING';
+-- The user who finished mapping the task and the user who validated it
+ALTER TABLE tasks ADD COLUMN mapper TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN validator TEXT NOT NULL DEFAULT '';
+
+INSERT INTO db_versions VALUES ('016');
+
+END TRANSACTION;
@@ -0,0 +1,36 @@
+BEGIN TRANSACTION;
+
+-- Replaces the "owner" and "users" columns of the projects table. The ID keeps the order in which members were added.
+CREATE TABLE project_members
+(
+	id         SERIAL PRIMARY KEY NOT NULL,
+	project_id INT                NOT NULL,
+	user_id    TEXT               NOT NULL,
+	-- One of 'OWNER', 'MODERATOR', 'MEMBER' and 'VIEWER'
+	role       TEXT               NOT NULL,
+	UNIQUE (project_id, user_id)
+);
+
+ALTER TABLE project_members ADD FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE;
+
+-- Each project has exactly one owner
+CREATE UNIQUE INDEX project_members_owner_index ON project_members (project_id) WHERE role = 'OWNER';
+
+INSERT INTO project_members (project_id, user_id, role)
+SELECT p.id, u.user_id, CASE WHEN u.user_id = p.owner THEN 'OWNER' ELSE 'MEMBER' END
+FROM projects p, unnest(p.users) WITH ORDINALITY AS u(user_id, position)
+ORDER BY p.id, u.position
+ON CONFLICT DO NOTHING;
+
+-- Just in case there are owners who are not in the list of users
+INSERT INTO project_members (project_id, user_id, role)
+SELECT p.id, p.owner, 'OWNER'
+FROM projects p
+WHERE NOT EXISTS(SELECT 1 FROM project_members m WHERE m.project_id = p.id AND m.user_id = p.owner);
+
+ALTER TABLE projects DROP COLUMN owner;
+ALTER TABLE projects DROP COLUMN users;
+
+INSERT INTO db_versions VALUES ('017');
+
+END TRANSACTION;
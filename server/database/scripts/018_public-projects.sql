@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+-- Every authenticated user can join public projects
+ALTER TABLE projects ADD COLUMN public BOOLEAN NOT NULL DEFAULT false;
+
+INSERT INTO db_versions VALUES ('018');
+
+END TRANSACTION;
@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+-- Spatial functions are used to analyze the task geometries of a project
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+INSERT INTO db_versions VALUES ('019');
+
+END TRANSACTION;
@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+-- Optional area of the project as GeoJSON geometry
+ALTER TABLE projects ADD COLUMN boundary TEXT;
+
+INSERT INTO db_versions VALUES ('020');
+
+END TRANSACTION;
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+-- The boundary is stored as PostGIS geometry instead of a GeoJSON string
+ALTER TABLE projects ALTER COLUMN boundary TYPE geometry(Geometry, 4326) USING ST_GeomFromGeoJSON(boundary);
+CREATE INDEX projects_boundary_index ON projects USING GIST (boundary);
+
+INSERT INTO db_versions VALUES ('021');
+
+END TRANSACTION;
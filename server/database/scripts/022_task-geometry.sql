@@ -0,0 +1,19 @@
+BEGIN TRANSACTION;
+
+-- PostGIS geometry of the GeoJSON feature in the "geometry" column
+ALTER TABLE tasks ADD COLUMN geom geometry(MultiPolygon, 4326);
+UPDATE tasks SET geom = ST_Multi(ST_CollectionExtract(ST_MakeValid(ST_GeomFromGeoJSON(geometry::json->'geometry')), 3));
+
+-- Properties of the GeoJSON feature (e.g. the name of the task). NULL when the feature has no properties.
+ALTER TABLE tasks ADD COLUMN properties JSONB;
+UPDATE tasks SET properties = NULLIF(geometry::jsonb->'properties', 'null'::jsonb);
+
+-- The GeoJSON feature is intentionally not kept: Geometry and properties are stored in the columns above and the
+-- feature is built from them when reading a task, so a TEXT copy would only get out of sync.
+ALTER TABLE tasks DROP COLUMN geometry;
+ALTER TABLE tasks RENAME COLUMN geom TO geometry;
+CREATE INDEX tasks_geometry_index ON tasks USING GIST (geometry);
+
+INSERT INTO db_versions VALUES ('022');
+
+END TRANSACTION;
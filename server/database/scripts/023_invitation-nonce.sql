@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+-- Part of the signature of all invitations to a project. Changing it revokes all existing invitations of the project.
+ALTER TABLE projects ADD COLUMN invitation_nonce TEXT NOT NULL DEFAULT gen_random_uuid()::text;
+
+INSERT INTO db_versions VALUES ('023');
+
+END TRANSACTION;
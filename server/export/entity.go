@@ -1,14 +1,57 @@
 package export
 
-import "time"
+import (
+	"stm/permission"
+	"stm/project"
+	"time"
+)
+
+type Format string
+
+const (
+	StmFormat     Format = "stm"     // The own format of STM, which can be imported again.
+	GeoJsonFormat Format = "geojson" // A GeoJSON feature collection containing all tasks.
+	OsmFormat     Format = "osm"     // An OSM XML file containing the task boundaries as ways and relations.
+	GpxFormat     Format = "gpx"     // A GPX file containing the task boundaries as tracks.
+	KmlFormat     Format = "kml"     // A KML file containing the tasks as placemarks styled by their state.
+	HotTmFormat   Format = "hottm"   // The task grid exported by the HOT Tasking Manager. This can only be imported.
+)
+
+type TaskState string
+
+const (
+	Untouched  TaskState = "untouched"   // No process points have been set.
+	InProgress TaskState = "in_progress" // Some but not all process points have been set.
+	Done       TaskState = "done"        // All process points have been set.
+)
+
+const (
+	// CurrentSchemaVersion is the version of the ProjectExport format created by this server. Exports of older
+	// versions are migrated when importing them.
+	CurrentSchemaVersion = 3
+)
 
 type ProjectExport struct {
-	Name         string        `json:"name"`
-	Users        []string      `json:"users"`
-	Owner        string        `json:"owner"`
-	Description  string        `json:"description"`
-	CreationDate *time.Time    `json:"creationDate"`
-	Tasks        []*TaskExport `json:"tasks"`
+	SchemaVersion     int                    `json:"schemaVersion"` // The version of this format. Exports without this field have version 1.
+	Name              string                 `json:"name"`
+	Users             []string               `json:"users"`
+	Owner             string                 `json:"owner"`
+	Members           []*MemberExport        `json:"members"` // Since version 2.
+	Description       string                 `json:"description"`
+	CreationDate      *time.Time             `json:"creationDate"`
+	JosmDataSource    project.JosmDataSource `json:"josmDataSource"`    // Since version 2.
+	NeedsAssignment   bool                   `json:"needsAssignment"`   // Since version 2. Only informative, this is determined by the number of members.
+	AssignmentTimeout int                    `json:"assignmentTimeout"` // Since version 2.
+	NeedsValidation   bool                   `json:"needsValidation"`   // Since version 2.
+	Public            bool                   `json:"public"`            // Since version 2.
+	Boundary          string                 `json:"boundary"`          // Since version 3. GeoJSON geometry of the project area, empty when there's no boundary.
+	Comments          []*CommentExport       `json:"comments"`          // Since version 2.
+	Tasks             []*TaskExport          `json:"tasks"`
+}
+
+type MemberExport struct {
+	UserId string          `json:"userId"`
+	Role   permission.Role `json:"role"`
 }
 
 type TaskExport struct {
@@ -17,5 +60,12 @@ type TaskExport struct {
 	MaxProcessPoints int    `json:"maxProcessPoints"`
 	Geometry         string `json:"geometry"`
 	// TODO Use "Id" as suffix?
-	AssignedUser string `json:"assignedUser"`
+	AssignedUser string           `json:"assignedUser"`
+	Comments     []*CommentExport `json:"comments"` // Since version 2.
+}
+
+type CommentExport struct {
+	Text         string     `json:"text"`
+	AuthorId     string     `json:"authorId"`
+	CreationDate *time.Time `json:"creationDate"`
 }
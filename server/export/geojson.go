@@ -0,0 +1,138 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+	"math"
+	"stm/task"
+	"stm/util"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Maximum process points of imported tasks, which don't specify them in their properties.
+	defaultMaxProcessPoints = 100
+
+	// Properties of the features containing the process points. They're used for exports and imports, so that exported
+	// feature collections can be imported again.
+	processPointsProperty    = "process_points"
+	maxProcessPointsProperty = "max_process_points"
+)
+
+// toFeatureCollection turns the tasks into a GeoJSON feature collection. Each task is a feature with its original
+// geometry. The properties contain the state of the task, existing properties of the task geometry are kept. The
+// process points use the same properties as the import of feature collections.
+func toFeatureCollection(tasks []*task.Task) (*geojson.FeatureCollection, error) {
+	collection := geojson.NewFeatureCollection()
+
+	for _, t := range tasks {
+		feature, err := geojson.UnmarshalFeature([]byte(t.Geometry))
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid GeoJSON of task %s", t.Id))
+		}
+
+		if feature.Properties == nil {
+			feature.Properties = make(map[string]interface{})
+		}
+		feature.SetProperty("name", t.Name)
+		feature.SetProperty(processPointsProperty, t.ProcessPoints)
+		feature.SetProperty(maxProcessPointsProperty, t.MaxProcessPoints)
+		feature.SetProperty("assigned_user", t.AssignedUser)
+		feature.SetProperty("progress", getProgress(t))
+
+		collection.AddFeature(feature)
+	}
+
+	return collection, nil
+}
+
+// toTaskDrafts turns the features of the collection into task drafts. The properties "max_process_points" and
+// "process_points" of the features are used as process points of the tasks, all other properties (e.g. "name") are
+// kept. Only features with a polygon or multi-polygon geometry are allowed. The problems of all invalid features are
+// returned at once as DraftError.
+func toTaskDrafts(collection *geojson.FeatureCollection) ([]task.DraftDto, error) {
+	if len(collection.Features) == 0 {
+		return nil, &util.InvalidRequestError{Err: errors.New("feature collection does not contain any features")}
+	}
+
+	taskDrafts := make([]task.DraftDto, len(collection.Features))
+	draftError := &task.DraftError{}
+	addProblem := func(index int, problem string) {
+		draftError.Problems = append(draftError.Problems, fmt.Sprintf("feature %d: %s", index, problem))
+	}
+
+	for i, feature := range collection.Features {
+		if feature.Geometry == nil {
+			addProblem(i, "feature has no geometry")
+			continue
+		}
+		if !feature.Geometry.IsPolygon() && !feature.Geometry.IsMultiPolygon() {
+			addProblem(i, fmt.Sprintf("geometry of type %s not allowed, only \"%s\" and \"%s\" are allowed", feature.Geometry.Type, geojson.GeometryPolygon, geojson.GeometryMultiPolygon))
+			continue
+		}
+
+		maxProcessPoints, err := getIntProperty(feature, maxProcessPointsProperty, defaultMaxProcessPoints)
+		if err != nil {
+			addProblem(i, err.Error())
+			continue
+		}
+
+		processPoints, err := getIntProperty(feature, processPointsProperty, 0)
+		if err != nil {
+			addProblem(i, err.Error())
+			continue
+		}
+
+		if processPoints < 0 || processPoints > maxProcessPoints {
+			addProblem(i, fmt.Sprintf("%d process points are not between 0 and the maximum of %d", processPoints, maxProcessPoints))
+			continue
+		}
+
+		geometry, err := json.Marshal(feature)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to marshal feature %d", i)
+		}
+
+		taskDrafts[i] = task.DraftDto{
+			MaxProcessPoints: maxProcessPoints,
+			ProcessPoints:    processPoints,
+			Geometry:         string(geometry),
+		}
+	}
+
+	if len(draftError.Problems) > 0 {
+		return nil, draftError
+	}
+
+	return taskDrafts, nil
+}
+
+// getIntProperty reads the property as integer. Numbers stored as strings, which some tools produce, are accepted as
+// well. The fallback is returned when the property doesn't exist.
+func getIntProperty(feature *geojson.Feature, key string, fallback int) (int, error) {
+	value, ok := feature.Properties[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, errors.New(fmt.Sprintf("property '%s' must be an integer but was %v", key, v))
+		}
+		return int(v), nil
+	case string:
+		number, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, errors.Wrapf(err, "property '%s' must be an integer but was '%s'", key, v)
+		}
+		return number, nil
+	}
+
+	return 0, errors.New(fmt.Sprintf("property '%s' must be an integer but was %v", key, value))
+}
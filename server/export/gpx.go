@@ -0,0 +1,93 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+	"stm/geometry"
+	"stm/task"
+)
+
+type gpxData struct {
+	XMLName xml.Name    `xml:"gpx"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Version string      `xml:"version,attr"`
+	Creator string      `xml:"creator,attr"`
+	Tracks  []*gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name        string             `xml:"name"`
+	Description string             `xml:"desc,omitempty"`
+	Segments    []*gpxTrackSegment `xml:"trkseg"`
+}
+
+type gpxTrackSegment struct {
+	Points []*gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat float64 `xml:"lat,attr"`
+	Lon float64 `xml:"lon,attr"`
+}
+
+// toGpx turns the tasks into a GPX document. Each task is a track and each ring of the task geometry is a segment of
+// this track.
+func toGpx(tasks []*task.Task) ([]byte, error) {
+	data := &gpxData{
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+		Version: "1.1",
+		Creator: "SimpleTaskManager",
+		Tracks:  make([]*gpxTrack, 0, len(tasks)),
+	}
+
+	for _, t := range tasks {
+		polygons, err := toTaskPolygons(t)
+		if err != nil {
+			return nil, err
+		}
+
+		track := &gpxTrack{
+			Name:        getTaskName(t),
+			Description: getTaskDescription(t),
+			Segments:    make([]*gpxTrackSegment, 0),
+		}
+
+		for _, polygon := range polygons {
+			for _, ring := range polygon {
+				segment := &gpxTrackSegment{
+					Points: make([]*gpxPoint, len(ring)),
+				}
+				for i, point := range ring {
+					segment.Points[i] = &gpxPoint{Lon: point[0], Lat: point[1]}
+				}
+				track.Segments = append(track.Segments, segment)
+			}
+		}
+
+		data.Tracks = append(data.Tracks, track)
+	}
+
+	xmlBytes, err := xml.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling GPX data")
+	}
+
+	return append([]byte(xml.Header), xmlBytes...), nil
+}
+
+// toTaskPolygons parses the geometry of the task into a list of polygons.
+func toTaskPolygons(t *task.Task) ([][][][]float64, error) {
+	feature, err := geojson.UnmarshalFeature([]byte(t.Geometry))
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("invalid GeoJSON of task %s", t.Id))
+	}
+
+	polygons, err := geometry.ToPolygons(feature.Geometry)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("unable to export geometry of task %s", t.Id))
+	}
+
+	return polygons, nil
+}
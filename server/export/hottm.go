@@ -0,0 +1,74 @@
+package export
+
+import (
+	"fmt"
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+	"stm/task"
+	"stm/util"
+)
+
+// Task states of the HOT Tasking Manager as found in the "taskStatus" property of its task grid export.
+const (
+	hotTmReady               = "READY"
+	hotTmLockedForMapping    = "LOCKED_FOR_MAPPING"
+	hotTmInvalidated         = "INVALIDATED"
+	hotTmMapped              = "MAPPED"
+	hotTmLockedForValidation = "LOCKED_FOR_VALIDATION"
+	hotTmValidated           = "VALIDATED"
+	hotTmBadImagery          = "BADIMAGERY"
+)
+
+// toTaskDraftsFromHotTm turns the task grid of the HOT Tasking Manager into task drafts. Tasks which still need to be
+// mapped get no process points, all other tasks are finished. Tasks with bad imagery are treated as finished as well,
+// because nobody can map them anyway. The task ID of the Tasking Manager becomes the name of the task. The problems of
+// all invalid features are returned at once as DraftError.
+func toTaskDraftsFromHotTm(collection *geojson.FeatureCollection) ([]task.DraftDto, error) {
+	if len(collection.Features) == 0 {
+		return nil, &util.InvalidRequestError{Err: errors.New("feature collection does not contain any features")}
+	}
+
+	draftError := &task.DraftError{}
+	addProblem := func(index int, problem string) {
+		draftError.Problems = append(draftError.Problems, fmt.Sprintf("feature %d: %s", index, problem))
+	}
+
+	for i, feature := range collection.Features {
+		status, ok := feature.Properties["taskStatus"].(string)
+		if !ok {
+			addProblem(i, "feature has no 'taskStatus' property")
+			continue
+		}
+
+		taskId, err := getIntProperty(feature, "taskId", -1)
+		if err != nil {
+			addProblem(i, err.Error())
+			continue
+		}
+		if taskId < 0 {
+			addProblem(i, "feature has no 'taskId' property")
+			continue
+		}
+
+		processPoints := 0
+		switch status {
+		case hotTmReady, hotTmLockedForMapping, hotTmInvalidated:
+			processPoints = 0
+		case hotTmMapped, hotTmLockedForValidation, hotTmValidated, hotTmBadImagery:
+			processPoints = defaultMaxProcessPoints
+		default:
+			addProblem(i, fmt.Sprintf("unknown task status '%s'", status))
+			continue
+		}
+
+		feature.SetProperty("name", fmt.Sprintf("%d", taskId))
+		feature.SetProperty(maxProcessPointsProperty, defaultMaxProcessPoints)
+		feature.SetProperty(processPointsProperty, processPoints)
+	}
+
+	if len(draftError.Problems) > 0 {
+		return nil, draftError
+	}
+
+	return toTaskDrafts(collection)
+}
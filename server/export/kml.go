@@ -0,0 +1,149 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"stm/task"
+	"strings"
+)
+
+type kmlData struct {
+	XMLName  xml.Name     `xml:"kml"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Document *kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string          `xml:"name"`
+	Styles     []*kmlStyle     `xml:"Style"`
+	Placemarks []*kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlStyle struct {
+	Id        string        `xml:"id,attr"`
+	LineStyle *kmlLineStyle `xml:"LineStyle"`
+	PolyStyle *kmlPolyStyle `xml:"PolyStyle"`
+}
+
+type kmlLineStyle struct {
+	Color string  `xml:"color"`
+	Width float64 `xml:"width"`
+}
+
+type kmlPolyStyle struct {
+	Color string `xml:"color"`
+}
+
+type kmlPlacemark struct {
+	Name          string            `xml:"name"`
+	Description   string            `xml:"description,omitempty"`
+	StyleUrl      string            `xml:"styleUrl"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry"`
+}
+
+type kmlMultiGeometry struct {
+	Polygons []*kmlPolygon `xml:"Polygon"`
+}
+
+type kmlPolygon struct {
+	OuterBoundary   *kmlBoundary   `xml:"outerBoundaryIs"`
+	InnerBoundaries []*kmlBoundary `xml:"innerBoundaryIs"`
+}
+
+type kmlBoundary struct {
+	LinearRing *kmlLinearRing `xml:"LinearRing"`
+}
+
+type kmlLinearRing struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// Colors of the tasks per state in the KML format "aabbggrr" (alpha, blue, green, red). The fill colors are
+// semi-transparent, so that the map below is still visible.
+var kmlStateColors = map[TaskState]struct {
+	line string
+	fill string
+}{
+	Untouched:  {line: "ff0000ff", fill: "400000ff"},
+	InProgress: {line: "ff00a5ff", fill: "4000a5ff"},
+	Done:       {line: "ff00c000", fill: "4000c000"},
+}
+
+// toKml turns the tasks into a KML document with one placemark per task. The placemarks are styled by the state of the
+// task: Untouched tasks are red, tasks in progress are orange and done tasks are green.
+func toKml(projectName string, tasks []*task.Task) ([]byte, error) {
+	document := &kmlDocument{
+		Name:       projectName,
+		Styles:     make([]*kmlStyle, 0, len(kmlStateColors)),
+		Placemarks: make([]*kmlPlacemark, 0, len(tasks)),
+	}
+
+	for _, state := range []TaskState{Untouched, InProgress, Done} {
+		colors := kmlStateColors[state]
+		document.Styles = append(document.Styles, &kmlStyle{
+			Id:        string(state),
+			LineStyle: &kmlLineStyle{Color: colors.line, Width: 2},
+			PolyStyle: &kmlPolyStyle{Color: colors.fill},
+		})
+	}
+
+	for _, t := range tasks {
+		polygons, err := toTaskPolygons(t)
+		if err != nil {
+			return nil, err
+		}
+
+		placemark := &kmlPlacemark{
+			Name:        getTaskName(t),
+			Description: getTaskDescription(t),
+			StyleUrl:    "#" + string(getState(t)),
+			MultiGeometry: &kmlMultiGeometry{
+				Polygons: make([]*kmlPolygon, 0, len(polygons)),
+			},
+		}
+
+		for _, polygon := range polygons {
+			if len(polygon) == 0 {
+				continue
+			}
+
+			kmlPolygon := &kmlPolygon{
+				OuterBoundary:   toKmlBoundary(polygon[0]),
+				InnerBoundaries: make([]*kmlBoundary, 0, len(polygon)-1),
+			}
+			for _, ring := range polygon[1:] {
+				kmlPolygon.InnerBoundaries = append(kmlPolygon.InnerBoundaries, toKmlBoundary(ring))
+			}
+
+			placemark.MultiGeometry.Polygons = append(placemark.MultiGeometry.Polygons, kmlPolygon)
+		}
+
+		document.Placemarks = append(document.Placemarks, placemark)
+	}
+
+	data := &kmlData{
+		Xmlns:    "http://www.opengis.net/kml/2.2",
+		Document: document,
+	}
+
+	xmlBytes, err := xml.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling KML data")
+	}
+
+	return append([]byte(xml.Header), xmlBytes...), nil
+}
+
+func toKmlBoundary(ring [][]float64) *kmlBoundary {
+	coordinates := make([]string, len(ring))
+	for i, point := range ring {
+		coordinates[i] = fmt.Sprintf("%v,%v", point[0], point[1])
+	}
+
+	return &kmlBoundary{
+		LinearRing: &kmlLinearRing{
+			Coordinates: strings.Join(coordinates, " "),
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package export
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"stm/permission"
+	"stm/project"
+	"stm/util"
+)
+
+// migrateProjectExport brings an export of an older schema version to the current version. Exports without a schema
+// version have been created before the version has been introduced and therefore have version 1.
+func migrateProjectExport(projectExport *ProjectExport) error {
+	if projectExport.SchemaVersion == 0 {
+		projectExport.SchemaVersion = 1
+	}
+
+	if projectExport.SchemaVersion < 0 || projectExport.SchemaVersion > CurrentSchemaVersion {
+		return &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("unsupported schema version %d, supported are versions 1 to %d", projectExport.SchemaVersion, CurrentSchemaVersion))}
+	}
+
+	for projectExport.SchemaVersion < CurrentSchemaVersion {
+		switch projectExport.SchemaVersion {
+		case 1:
+			migrateFromVersion1(projectExport)
+		case 2:
+			// Version 3 only added the optional boundary, so there's nothing to migrate
+		}
+
+		projectExport.SchemaVersion++
+	}
+
+	return nil
+}
+
+// migrateFromVersion1 adds the settings, roles and comments, which didn't exist in version 1. The settings get the
+// default values a new project would get.
+func migrateFromVersion1(projectExport *ProjectExport) {
+	projectExport.JosmDataSource = project.OSM
+	projectExport.Comments = make([]*CommentExport, 0)
+
+	projectExport.Members = make([]*MemberExport, len(projectExport.Users))
+	for i, u := range projectExport.Users {
+		role := permission.Member
+		if u == projectExport.Owner {
+			role = permission.Owner
+		}
+
+		projectExport.Members[i] = &MemberExport{
+			UserId: u,
+			Role:   role,
+		}
+	}
+
+	for _, t := range projectExport.Tasks {
+		t.Comments = make([]*CommentExport, 0)
+	}
+}
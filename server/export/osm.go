@@ -0,0 +1,171 @@
+package export
+
+import (
+	"encoding/xml"
+	"github.com/pkg/errors"
+	"stm/task"
+	"strconv"
+)
+
+type osmData struct {
+	XMLName   xml.Name       `xml:"osm"`
+	Version   string         `xml:"version,attr"`
+	Generator string         `xml:"generator,attr"`
+	Upload    string         `xml:"upload,attr"`
+	Nodes     []*osmNode     `xml:"node"`
+	Ways      []*osmWay      `xml:"way"`
+	Relations []*osmRelation `xml:"relation"`
+}
+
+type osmNode struct {
+	Id  int64   `xml:"id,attr"`
+	Lat float64 `xml:"lat,attr"`
+	Lon float64 `xml:"lon,attr"`
+}
+
+type osmWay struct {
+	Id       int64         `xml:"id,attr"`
+	NodeRefs []*osmNodeRef `xml:"nd"`
+	Tags     []*osmTag     `xml:"tag"`
+}
+
+type osmNodeRef struct {
+	Ref int64 `xml:"ref,attr"`
+}
+
+type osmRelation struct {
+	Id      int64        `xml:"id,attr"`
+	Members []*osmMember `xml:"member"`
+	Tags    []*osmTag    `xml:"tag"`
+}
+
+type osmMember struct {
+	Type string `xml:"type,attr"`
+	Ref  int64  `xml:"ref,attr"`
+	Role string `xml:"role,attr"`
+}
+
+type osmTag struct {
+	Key   string `xml:"k,attr"`
+	Value string `xml:"v,attr"`
+}
+
+// osmWriter creates OSM objects with negative IDs, which is how JOSM recognizes new objects.
+type osmWriter struct {
+	data   *osmData
+	lastId int64
+}
+
+// toOsmXml turns the tasks into an OSM XML document. Simple polygons become closed ways, polygons with holes and
+// multi-polygons become multipolygon relations. The ways or relations carry the name and state of the task as tags.
+// The document is marked as not uploadable, so that the task boundaries don't end up in the OSM database by accident.
+func toOsmXml(tasks []*task.Task) ([]byte, error) {
+	w := &osmWriter{
+		data: &osmData{
+			Version:   "0.6",
+			Generator: "SimpleTaskManager",
+			Upload:    "never",
+			Nodes:     make([]*osmNode, 0),
+			Ways:      make([]*osmWay, 0),
+			Relations: make([]*osmRelation, 0),
+		},
+	}
+
+	for _, t := range tasks {
+		polygons, err := toTaskPolygons(t)
+		if err != nil {
+			return nil, err
+		}
+
+		tags := toOsmTags(t)
+
+		// A simple polygon without holes can be represented by a single way
+		if len(polygons) == 1 && len(polygons[0]) == 1 {
+			way := w.addWay(polygons[0][0])
+			way.Tags = tags
+			continue
+		}
+
+		relation := &osmRelation{
+			Id:      w.nextId(),
+			Members: make([]*osmMember, 0),
+			Tags:    append([]*osmTag{{Key: "type", Value: "multipolygon"}}, tags...),
+		}
+		for _, polygon := range polygons {
+			for i, ring := range polygon {
+				role := "inner"
+				if i == 0 {
+					role = "outer"
+				}
+
+				way := w.addWay(ring)
+				relation.Members = append(relation.Members, &osmMember{Type: "way", Ref: way.Id, Role: role})
+			}
+		}
+		w.data.Relations = append(w.data.Relations, relation)
+	}
+
+	xmlBytes, err := xml.MarshalIndent(w.data, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling OSM data")
+	}
+
+	return append([]byte(xml.Header), xmlBytes...), nil
+}
+
+func (w *osmWriter) nextId() int64 {
+	w.lastId--
+	return w.lastId
+}
+
+// addWay creates a closed way with a node for each point of the ring. The ring is expected to be closed, so the last
+// point is not turned into a separate node.
+func (w *osmWriter) addWay(ring [][]float64) *osmWay {
+	way := &osmWay{
+		NodeRefs: make([]*osmNodeRef, 0, len(ring)),
+		Tags:     make([]*osmTag, 0),
+	}
+
+	points := ring
+	if len(ring) > 1 && ring[0][0] == ring[len(ring)-1][0] && ring[0][1] == ring[len(ring)-1][1] {
+		points = ring[:len(ring)-1]
+	}
+
+	for _, point := range points {
+		node := &osmNode{
+			Id:  w.nextId(),
+			Lon: point[0],
+			Lat: point[1],
+		}
+		w.data.Nodes = append(w.data.Nodes, node)
+		way.NodeRefs = append(way.NodeRefs, &osmNodeRef{Ref: node.Id})
+	}
+	if len(way.NodeRefs) > 0 {
+		way.NodeRefs = append(way.NodeRefs, way.NodeRefs[0])
+	}
+
+	way.Id = w.nextId()
+	w.data.Ways = append(w.data.Ways, way)
+
+	return way
+}
+
+func toOsmTags(t *task.Task) []*osmTag {
+	tags := make([]*osmTag, 0)
+	if t.Name != "" {
+		tags = append(tags, &osmTag{Key: "name", Value: t.Name})
+	}
+
+	tags = append(tags,
+		&osmTag{Key: "stm:task_id", Value: t.Id},
+		&osmTag{Key: "stm:state", Value: string(getState(t))},
+		&osmTag{Key: "stm:process_points", Value: strconv.Itoa(t.ProcessPoints)},
+		&osmTag{Key: "stm:max_process_points", Value: strconv.Itoa(t.MaxProcessPoints)},
+	)
+
+	if t.AssignedUser != "" {
+		tags = append(tags, &osmTag{Key: "stm:assigned_user", Value: t.AssignedUser})
+	}
+
+	return tags
+}
@@ -1,6 +1,10 @@
 package export
 
 import (
+	"fmt"
+	geojson "github.com/paulmach/go.geojson"
+	"stm/comment"
+	"stm/permission"
 	"stm/project"
 	"stm/task"
 	"stm/util"
@@ -9,12 +13,14 @@ import (
 type Service struct {
 	*util.Logger
 	projectService *project.Service
+	taskService    *task.Service
 }
 
-func Init(logger *util.Logger, projectService *project.Service) *Service {
+func Init(logger *util.Logger, projectService *project.Service, taskService *task.Service) *Service {
 	return &Service{
 		Logger:         logger,
 		projectService: projectService,
+		taskService:    taskService,
 	}
 }
 
@@ -27,7 +33,56 @@ func (s *Service) ExportProject(projectId string, potentialMemberId string) (*Pr
 	return toProjectExport(project), nil
 }
 
+// ExportProjectAsGeoJson returns all tasks of the project as GeoJSON feature collection. The properties of each
+// feature contain the name, process points, assigned user and the progress of the task.
+func (s *Service) ExportProjectAsGeoJson(projectId string, potentialMemberId string) (*geojson.FeatureCollection, error) {
+	project, err := s.projectService.GetProject(projectId, potentialMemberId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toFeatureCollection(project.Tasks)
+}
+
+// ExportProjectAsOsm returns the task boundaries of the project as OSM XML file, which can be opened in JOSM.
+func (s *Service) ExportProjectAsOsm(projectId string, potentialMemberId string) ([]byte, error) {
+	project, err := s.projectService.GetProject(projectId, potentialMemberId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toOsmXml(project.Tasks)
+}
+
+// ExportProjectAsGpx returns the task boundaries of the project as GPX file, which can be used in apps like OsmAnd.
+func (s *Service) ExportProjectAsGpx(projectId string, potentialMemberId string) ([]byte, error) {
+	project, err := s.projectService.GetProject(projectId, potentialMemberId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGpx(project.Tasks)
+}
+
+// ExportProjectAsKml returns the tasks of the project as KML file, which can be used in apps like Google Earth.
+func (s *Service) ExportProjectAsKml(projectId string, potentialMemberId string) ([]byte, error) {
+	project, err := s.projectService.GetProject(projectId, potentialMemberId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toKml(project.Name, project.Tasks)
+}
+
+// ImportProject creates a new project from the export including its settings and comments. Exports of older schema
+// versions are migrated first. The requesting user becomes the owner of the new project, the original owner becomes a
+// moderator.
 func (s *Service) ImportProject(projectExport *ProjectExport, requestingUserId string) (*project.Project, error) {
+	err := migrateProjectExport(projectExport)
+	if err != nil {
+		return nil, err
+	}
+
 	// Determine if the requesting user is part of this project. If not, then add him/her. It wouldn't make much sense
 	//if the requesting user won't be part of the project
 	alreadyContainsUser := false
@@ -42,10 +97,15 @@ func (s *Service) ImportProject(projectExport *ProjectExport, requestingUserId s
 	}
 
 	projectDraftDto := &project.DraftDto{
-		Name:        projectExport.Name,
-		Description: projectExport.Description,
-		Users:       projectExport.Users,
-		Owner:       requestingUserId,
+		Name:              projectExport.Name,
+		Description:       projectExport.Description,
+		Users:             projectExport.Users,
+		Owner:             requestingUserId,
+		JosmDataSource:    projectExport.JosmDataSource,
+		AssignmentTimeout: projectExport.AssignmentTimeout,
+		NeedsValidation:   projectExport.NeedsValidation,
+		Public:            projectExport.Public,
+		Boundary:          projectExport.Boundary,
 	}
 
 	taskDraftDtos := make([]task.DraftDto, len(projectExport.Tasks))
@@ -54,21 +114,182 @@ func (s *Service) ImportProject(projectExport *ProjectExport, requestingUserId s
 		taskDraftDtos[i] = task.DraftDto{
 			MaxProcessPoints: t.MaxProcessPoints,
 			ProcessPoints:    t.ProcessPoints,
-			Geometry:         t.Geometry,
+			Geometry:         withTaskName(t.Geometry, t.Name),
 		}
 	}
 
+	addedProject, err := s.projectService.AddProjectWithTasks(projectDraftDto, taskDraftDtos)
+	if err != nil {
+		return nil, err
+	}
+
+	// The added tasks are in the same order as the task drafts
+	taskIds := make([]string, len(addedProject.Tasks))
+	for i, t := range addedProject.Tasks {
+		taskIds[i] = t.Id
+	}
+
+	// Assign the tasks before the roles are set, because viewers are not allowed to be assigned
+	err = s.importAssignments(taskIds, projectExport.Tasks, projectExport.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.importRoles(addedProject.Id, projectExport.Members, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.projectService.ImportComments(addedProject.Id, toComments(projectExport.Comments))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, t := range projectExport.Tasks {
+		err = s.taskService.ImportComments(taskIds[i], toComments(t.Comments))
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.Log("Imported project %s with schema version %d", addedProject.Id, projectExport.SchemaVersion)
+
+	// Get project again to also have the imported roles and comments
+	return s.projectService.GetProject(addedProject.Id, requestingUserId)
+}
+
+// importAssignments assigns the users of the exported tasks to the added tasks with the given IDs. Assigned users, who
+// are no members of the project, are ignored.
+func (s *Service) importAssignments(taskIds []string, tasks []*TaskExport, users []string) error {
+	isMember := make(map[string]bool, len(users))
+	for _, u := range users {
+		isMember[u] = true
+	}
+
+	for i, t := range tasks {
+		if t.AssignedUser == "" {
+			continue
+		}
+		if !isMember[t.AssignedUser] {
+			s.Log("Assigned user %s of task %s is no member of the project and is not assigned", t.AssignedUser, taskIds[i])
+			continue
+		}
+
+		_, err := s.taskService.AssignUser(taskIds[i], t.AssignedUser)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withTaskName adds the name of the task to the properties of the geometry feature, if the feature doesn't contain a
+// name yet. Invalid geometries are returned unchanged, they're rejected when the task is added.
+func withTaskName(taskGeometry string, name string) string {
+	if name == "" {
+		return taskGeometry
+	}
+
+	feature, err := geojson.UnmarshalFeature([]byte(taskGeometry))
+	if err != nil {
+		return taskGeometry
+	}
+	if _, ok := feature.Properties["name"]; ok {
+		return taskGeometry
+	}
+
+	feature.SetProperty("name", name)
+	geometryBytes, err := feature.MarshalJSON()
+	if err != nil {
+		return taskGeometry
+	}
+
+	return string(geometryBytes)
+}
+
+// importRoles sets the roles of the members. All users are already members of the project, so only moderators and
+// viewers have to be set.
+func (s *Service) importRoles(projectId string, members []*MemberExport, requestingUserId string) error {
+	for _, m := range members {
+		if m.UserId == requestingUserId {
+			continue
+		}
+
+		role := m.Role
+		if role == permission.Owner {
+			role = permission.Moderator
+		}
+		if role == permission.Member {
+			continue
+		}
+
+		_, err := s.projectService.SetRole(projectId, m.UserId, role, requestingUserId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ImportFeatureCollection creates a new project with one task per feature of the collection. The requesting user
+// becomes the owner of the new project.
+func (s *Service) ImportFeatureCollection(collection *geojson.FeatureCollection, projectName string, requestingUserId string) (*project.Project, error) {
+	taskDraftDtos, err := toTaskDrafts(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.importTaskDrafts(taskDraftDtos, projectName, requestingUserId)
+}
+
+// ImportHotTmProject creates a new project from the task grid exported by the HOT Tasking Manager. The progress of the
+// tasks is kept. The requesting user becomes the owner of the new project.
+func (s *Service) ImportHotTmProject(collection *geojson.FeatureCollection, projectName string, requestingUserId string) (*project.Project, error) {
+	taskDraftDtos, err := toTaskDraftsFromHotTm(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.importTaskDrafts(taskDraftDtos, projectName, requestingUserId)
+}
+
+func (s *Service) importTaskDrafts(taskDraftDtos []task.DraftDto, projectName string, requestingUserId string) (*project.Project, error) {
+	projectDraftDto := &project.DraftDto{
+		Name:           projectName,
+		Users:          []string{requestingUserId},
+		Owner:          requestingUserId,
+		JosmDataSource: project.OSM,
+	}
+
 	return s.projectService.AddProjectWithTasks(projectDraftDto, taskDraftDtos)
 }
 
 func toProjectExport(project *project.Project) *ProjectExport {
+	members := make([]*MemberExport, len(project.Members))
+	for i, m := range project.Members {
+		members[i] = &MemberExport{
+			UserId: m.UserId,
+			Role:   m.Role,
+		}
+	}
+
 	return &ProjectExport{
-		Name:         project.Name,
-		Users:        project.Users,
-		Owner:        project.Owner,
-		Description:  project.Description,
-		CreationDate: project.CreationDate,
-		Tasks:        toTaskExport(project.Tasks),
+		SchemaVersion:     CurrentSchemaVersion,
+		Name:              project.Name,
+		Users:             project.Users,
+		Owner:             project.Owner,
+		Members:           members,
+		Description:       project.Description,
+		CreationDate:      project.CreationDate,
+		JosmDataSource:    project.JosmDataSource,
+		NeedsAssignment:   project.NeedsAssignment,
+		AssignmentTimeout: project.AssignmentTimeout,
+		NeedsValidation:   project.NeedsValidation,
+		Public:            project.Public,
+		Boundary:          project.Boundary,
+		Comments:          toCommentExport(project.Comments),
+		Tasks:             toTaskExport(project.Tasks),
 	}
 }
 
@@ -83,8 +304,72 @@ func toTaskExport(tasks []*task.Task) []*TaskExport {
 			MaxProcessPoints: task.MaxProcessPoints,
 			Geometry:         task.Geometry,
 			AssignedUser:     task.AssignedUser,
+			Comments:         toCommentExport(task.Comments),
 		}
 	}
 
 	return taskExport
 }
+
+func toCommentExport(comments []comment.Comment) []*CommentExport {
+	commentExport := make([]*CommentExport, len(comments))
+
+	for i, c := range comments {
+		commentExport[i] = &CommentExport{
+			Text:         c.Text,
+			AuthorId:     c.AuthorId,
+			CreationDate: c.CreationDate,
+		}
+	}
+
+	return commentExport
+}
+
+func toComments(commentExport []*CommentExport) []comment.Comment {
+	comments := make([]comment.Comment, len(commentExport))
+
+	for i, c := range commentExport {
+		comments[i] = comment.Comment{
+			Text:         c.Text,
+			AuthorId:     c.AuthorId,
+			CreationDate: c.CreationDate,
+		}
+	}
+
+	return comments
+}
+
+// getProgress returns the progress of the task in percent.
+func getProgress(t *task.Task) int {
+	if t.MaxProcessPoints <= 0 {
+		return 0
+	}
+	return 100 * t.ProcessPoints / t.MaxProcessPoints
+}
+
+// getTaskName returns the name of the task or a generic name containing the ID, if the task doesn't have a name.
+func getTaskName(t *task.Task) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprintf("Task %s", t.Id)
+}
+
+// getTaskDescription returns a human-readable summary of the state of the task.
+func getTaskDescription(t *task.Task) string {
+	description := fmt.Sprintf("%d of %d process points (%d%%)", t.ProcessPoints, t.MaxProcessPoints, getProgress(t))
+	if t.AssignedUser != "" {
+		description += fmt.Sprintf(", assigned to %s", t.AssignedUser)
+	}
+	return description
+}
+
+func getState(t *task.Task) TaskState {
+	if t.ProcessPoints <= 0 {
+		return Untouched
+	}
+	if t.ProcessPoints < t.MaxProcessPoints {
+		return InProgress
+	}
+	return Done
+}
@@ -2,7 +2,10 @@ package export
 
 import (
 	"database/sql"
+	"encoding/xml"
+	"fmt"
 	"github.com/hauke96/sigolo"
+	geojson "github.com/paulmach/go.geojson"
 	"github.com/pkg/errors"
 	"stm/comment"
 	"stm/config"
@@ -11,6 +14,7 @@ import (
 	"stm/task"
 	"stm/test"
 	"stm/util"
+	"strings"
 	"testing"
 	"time"
 )
@@ -40,7 +44,7 @@ func setup() {
 	taskService := task.Init(tx, logger, permissionStore, commentService, commentStore)
 	projectService := project.Init(tx, logger, taskService, permissionStore, commentService, commentStore)
 
-	s = Init(logger, projectService)
+	s = Init(logger, projectService, taskService)
 }
 
 func TestGetProjectExport(t *testing.T) {
@@ -73,6 +77,214 @@ func TestGetProjectExport(t *testing.T) {
 	})
 }
 
+func TestExportProjectAsGeoJson(t *testing.T) {
+	h.Run(t, func() error {
+		result, err := s.ExportProjectAsGeoJson("2", "Anna")
+		if err != nil {
+			return err
+		}
+
+		if len(result.Features) != 5 {
+			return errors.New(fmt.Sprintf("Expected 5 features but got %d", len(result.Features)))
+		}
+
+		// Task 3 has 50 of 100 process points and is the only task assigned to Maria
+		var feature *geojson.Feature
+		for _, f := range result.Features {
+			if f.Properties["assigned_user"] == "Maria" {
+				feature = f
+			}
+		}
+		if feature == nil {
+			return errors.New("Feature of task assigned to Maria not found")
+		}
+		if feature.Geometry == nil || !feature.Geometry.IsPolygon() {
+			return errors.New("Feature should have the polygon geometry of the task")
+		}
+		if feature.Properties["process_points"] != 50 || feature.Properties["max_process_points"] != 100 {
+			return errors.New(fmt.Sprintf("Process points not matching: %v", feature.Properties))
+		}
+		if feature.Properties["progress"] != 50 {
+			return errors.New(fmt.Sprintf("Progress not matching: %v", feature.Properties))
+		}
+
+		// The export can be imported again without losing the process points
+		imported, err := s.ImportFeatureCollection(result, "Imported project", "Anna")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Importing the exported feature collection should work: %s", err.Error()))
+		}
+		importedPoints, importedMaxPoints := 0, 0
+		for _, t := range imported.Tasks {
+			importedPoints += t.ProcessPoints
+			importedMaxPoints += t.MaxProcessPoints
+		}
+		if importedPoints != 154 || importedMaxPoints != 308 {
+			return errors.New(fmt.Sprintf("Process points should be kept but were %d/%d", importedPoints, importedMaxPoints))
+		}
+
+		_, err = s.ExportProjectAsGeoJson("2", "Peter")
+		if err == nil {
+			return errors.New("Non-member should not be able to export the project")
+		}
+
+		return nil
+	})
+}
+
+func TestExportProjectAsOsm(t *testing.T) {
+	h.Run(t, func() error {
+		result, err := s.ExportProjectAsOsm("2", "Anna")
+		if err != nil {
+			return err
+		}
+
+		var osm osmData
+		err = xml.Unmarshal(result, &osm)
+		if err != nil {
+			return errors.Wrap(err, "Export should be valid XML")
+		}
+
+		// All five tasks are simple polygons with four distinct points
+		if len(osm.Ways) != 5 || len(osm.Nodes) != 20 || len(osm.Relations) != 0 {
+			return errors.New(fmt.Sprintf("Unexpected number of objects: %d nodes, %d ways, %d relations", len(osm.Nodes), len(osm.Ways), len(osm.Relations)))
+		}
+
+		for _, way := range osm.Ways {
+			if way.Id >= 0 {
+				return errors.New(fmt.Sprintf("Way should have a negative ID but has %d", way.Id))
+			}
+			if len(way.NodeRefs) != 5 || way.NodeRefs[0].Ref != way.NodeRefs[4].Ref {
+				return errors.New(fmt.Sprintf("Way %d should be closed", way.Id))
+			}
+			if !hasOsmTag(way.Tags, "stm:state") {
+				return errors.New(fmt.Sprintf("Way %d should have a state tag", way.Id))
+			}
+		}
+
+		return nil
+	})
+}
+
+func TestExportMultiPolygonAsOsm(t *testing.T) {
+	h.Run(t, func() error {
+		tasks := []*task.Task{{
+			Id:               "1",
+			Name:             "multi",
+			ProcessPoints:    10,
+			MaxProcessPoints: 10,
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"MultiPolygon\",\"coordinates\":[[[[0,0],[4,0],[4,4],[0,4],[0,0]],[[1,1],[2,1],[2,2],[1,1]]],[[[5,5],[6,5],[6,6],[5,5]]]]},\"properties\":null}",
+		}}
+
+		result, err := toOsmXml(tasks)
+		if err != nil {
+			return err
+		}
+
+		var osm osmData
+		err = xml.Unmarshal(result, &osm)
+		if err != nil {
+			return errors.Wrap(err, "Export should be valid XML")
+		}
+
+		if len(osm.Relations) != 1 || len(osm.Ways) != 3 {
+			return errors.New(fmt.Sprintf("Expected one relation with three ways but got %d relations and %d ways", len(osm.Relations), len(osm.Ways)))
+		}
+
+		relation := osm.Relations[0]
+		roles := ""
+		for _, m := range relation.Members {
+			roles += m.Role + " "
+		}
+		if roles != "outer inner outer " {
+			return errors.New(fmt.Sprintf("Unexpected member roles: %s", roles))
+		}
+		if !hasOsmTag(relation.Tags, "type") || !hasOsmTag(relation.Tags, "name") {
+			return errors.New(fmt.Sprintf("Relation should be tagged as multipolygon with name: %v", relation.Tags))
+		}
+
+		return nil
+	})
+}
+
+func hasOsmTag(tags []*osmTag, key string) bool {
+	for _, tag := range tags {
+		if tag.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func TestExportProjectAsGpx(t *testing.T) {
+	h.Run(t, func() error {
+		result, err := s.ExportProjectAsGpx("2", "Anna")
+		if err != nil {
+			return err
+		}
+
+		var gpx gpxData
+		err = xml.Unmarshal(result, &gpx)
+		if err != nil {
+			return errors.Wrap(err, "Export should be valid XML")
+		}
+
+		if len(gpx.Tracks) != 5 {
+			return errors.New(fmt.Sprintf("Expected 5 tracks but got %d", len(gpx.Tracks)))
+		}
+
+		for _, track := range gpx.Tracks {
+			if !strings.HasPrefix(track.Name, "Task ") {
+				return errors.New(fmt.Sprintf("Unnamed tasks should get a generic name but got '%s'", track.Name))
+			}
+			if len(track.Segments) != 1 || len(track.Segments[0].Points) != 5 {
+				return errors.New(fmt.Sprintf("Track '%s' should have one segment with 5 points", track.Name))
+			}
+		}
+
+		return nil
+	})
+}
+
+func TestExportProjectAsKml(t *testing.T) {
+	h.Run(t, func() error {
+		result, err := s.ExportProjectAsKml("2", "Anna")
+		if err != nil {
+			return err
+		}
+
+		var kml kmlData
+		err = xml.Unmarshal(result, &kml)
+		if err != nil {
+			return errors.Wrap(err, "Export should be valid XML")
+		}
+
+		if kml.Document.Name != "Project 2" {
+			return errors.New(fmt.Sprintf("Document should have the project name but has '%s'", kml.Document.Name))
+		}
+		if len(kml.Document.Styles) != 3 {
+			return errors.New(fmt.Sprintf("Expected one style per state but got %d", len(kml.Document.Styles)))
+		}
+		if len(kml.Document.Placemarks) != 5 {
+			return errors.New(fmt.Sprintf("Expected 5 placemarks but got %d", len(kml.Document.Placemarks)))
+		}
+
+		styles := make(map[string]int)
+		for _, placemark := range kml.Document.Placemarks {
+			styles[placemark.StyleUrl]++
+			if len(placemark.MultiGeometry.Polygons) != 1 {
+				return errors.New(fmt.Sprintf("Placemark '%s' should have one polygon", placemark.Name))
+			}
+		}
+
+		// Task 4 is untouched, task 2 is done and the other tasks are in progress
+		if styles["#untouched"] != 1 || styles["#done"] != 1 || styles["#in_progress"] != 3 {
+			return errors.New(fmt.Sprintf("Unexpected styles of placemarks: %v", styles))
+		}
+
+		return nil
+	})
+}
+
 func TestImportProject(t *testing.T) {
 	h.Run(t, func() error {
 		// Arrange
@@ -120,6 +332,214 @@ func TestImportProject(t *testing.T) {
 		if len(result.Tasks) != 1 {
 			return errors.New("Number of tasks not matching")
 		}
+		if result.Tasks[0].Name != "task 1" || result.Tasks[0].AssignedUser != "345" {
+			return errors.New(fmt.Sprintf("Name and assigned user of task should be imported: %#v", result.Tasks[0]))
+		}
+
+		return nil
+	})
+}
+
+func TestImportFeatureCollection(t *testing.T) {
+	h.Run(t, func() error {
+		collection, err := geojson.UnmarshalFeatureCollection([]byte(`{"type":"FeatureCollection","features":[
+			{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[9.98,53.55],[9.99,53.55],[9.99,53.56],[9.98,53.55]]]},"properties":{"name":"first","max_process_points":10,"process_points":4}},
+			{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[9.99,53.55],[10.0,53.55],[10.0,53.56],[9.99,53.55]]]},"properties":{"name":"second","max_process_points":"20"}},
+			{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[10.0,53.55],[10.1,53.55],[10.1,53.56],[10.0,53.55]]]},"properties":null}
+		]}`))
+		if err != nil {
+			return err
+		}
+
+		result, err := s.ImportFeatureCollection(collection, "QGIS project", "123")
+		if err != nil {
+			return err
+		}
+
+		if result.Name != "QGIS project" || result.Owner != "123" {
+			return errors.New(fmt.Sprintf("Unexpected name '%s' or owner '%s'", result.Name, result.Owner))
+		}
+		if len(result.Tasks) != 3 {
+			return errors.New(fmt.Sprintf("Expected 3 tasks but got %d", len(result.Tasks)))
+		}
+
+		first, second, third := result.Tasks[0], result.Tasks[1], result.Tasks[2]
+		if first.Name != "first" || first.ProcessPoints != 4 || first.MaxProcessPoints != 10 {
+			return errors.New(fmt.Sprintf("First task not matching: %#v", first))
+		}
+		if second.Name != "second" || second.ProcessPoints != 0 || second.MaxProcessPoints != 20 {
+			return errors.New(fmt.Sprintf("Second task not matching: %#v", second))
+		}
+		if third.ProcessPoints != 0 || third.MaxProcessPoints != defaultMaxProcessPoints {
+			return errors.New(fmt.Sprintf("Third task should have default process points: %#v", third))
+		}
+
+		return nil
+	})
+}
+
+func TestImportInvalidFeatureCollection(t *testing.T) {
+	h.Run(t, func() error {
+		invalidCollections := []string{
+			// Point geometry
+			`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[9.98,53.55]},"properties":null}]}`,
+			// Process points not an integer
+			`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[9.98,53.55],[9.99,53.55],[9.99,53.56],[9.98,53.55]]]},"properties":{"process_points":"foo"}}]}`,
+			// Process points larger than maximum
+			`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[9.98,53.55],[9.99,53.55],[9.99,53.56],[9.98,53.55]]]},"properties":{"process_points":20,"max_process_points":10}}]}`,
+			// No features
+			`{"type":"FeatureCollection","features":[]}`,
+		}
+
+		for _, c := range invalidCollections {
+			collection, err := geojson.UnmarshalFeatureCollection([]byte(c))
+			if err != nil {
+				return err
+			}
+
+			// Invalid collections are caused by the client and must not be reported as server errors
+			var draftError *task.DraftError
+			var requestError *util.InvalidRequestError
+			_, err = s.ImportFeatureCollection(collection, "invalid", "123")
+			if !errors.As(err, &draftError) && !errors.As(err, &requestError) {
+				return errors.New(fmt.Sprintf("Importing feature collection should fail as invalid request: %s: %v", c, err))
+			}
+		}
+
+		// All invalid features are reported at once
+		collection, err := geojson.UnmarshalFeatureCollection([]byte(`{"type":"FeatureCollection","features":[
+			{"type":"Feature","geometry":{"type":"Point","coordinates":[9.98,53.55]},"properties":null},
+			{"type":"Feature","geometry":null,"properties":null}]}`))
+		if err != nil {
+			return err
+		}
+		var draftError *task.DraftError
+		_, err = s.ImportFeatureCollection(collection, "invalid", "123")
+		if !errors.As(err, &draftError) || len(draftError.Problems) != 2 || !strings.HasPrefix(draftError.Problems[1], "feature 1:") {
+			return errors.New(fmt.Sprintf("Expected problems of both features: %v", err))
+		}
+
+		return nil
+	})
+}
+
+func TestImportHotTmProject(t *testing.T) {
+	h.Run(t, func() error {
+		collection, err := geojson.UnmarshalFeatureCollection([]byte(`{"type":"FeatureCollection","features":[
+			{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[[[[9.98,53.55],[9.99,53.55],[9.99,53.56],[9.98,53.55]]]]},"properties":{"taskId":1,"taskX":1,"taskY":2,"taskZoom":16,"taskSplittable":true,"taskStatus":"READY"}},
+			{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[[[[9.99,53.55],[10.0,53.55],[10.0,53.56],[9.99,53.55]]]]},"properties":{"taskId":2,"taskX":2,"taskY":2,"taskZoom":16,"taskSplittable":true,"taskStatus":"MAPPED"}},
+			{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[[[[10.0,53.55],[10.1,53.55],[10.1,53.56],[10.0,53.55]]]]},"properties":{"taskId":3,"taskX":3,"taskY":2,"taskZoom":16,"taskSplittable":true,"taskStatus":"INVALIDATED"}},
+			{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[[[[10.1,53.55],[10.2,53.55],[10.2,53.56],[10.1,53.55]]]]},"properties":{"taskId":4,"taskX":4,"taskY":2,"taskZoom":16,"taskSplittable":true,"taskStatus":"VALIDATED"}}
+		]}`))
+		if err != nil {
+			return err
+		}
+
+		result, err := s.ImportHotTmProject(collection, "HOT project", "123")
+		if err != nil {
+			return err
+		}
+
+		if len(result.Tasks) != 4 {
+			return errors.New(fmt.Sprintf("Expected 4 tasks but got %d", len(result.Tasks)))
+		}
+
+		expectedProcessPoints := []int{0, defaultMaxProcessPoints, 0, defaultMaxProcessPoints}
+		for i, task := range result.Tasks {
+			if task.Name != fmt.Sprintf("%d", i+1) {
+				return errors.New(fmt.Sprintf("Task should be named after the task ID %d but is named '%s'", i+1, task.Name))
+			}
+			if task.ProcessPoints != expectedProcessPoints[i] || task.MaxProcessPoints != defaultMaxProcessPoints {
+				return errors.New(fmt.Sprintf("Process points of task %d not matching: %d/%d", i+1, task.ProcessPoints, task.MaxProcessPoints))
+			}
+		}
+
+		// Unknown status
+		var draftError *task.DraftError
+		collection.Features[0].Properties["taskStatus"] = "FOOBAR"
+		_, err = s.ImportHotTmProject(collection, "HOT project", "123")
+		if !errors.As(err, &draftError) {
+			return errors.New(fmt.Sprintf("Importing task with unknown status should fail as invalid request: %v", err))
+		}
+
+		// No features
+		var requestError *util.InvalidRequestError
+		_, err = s.ImportHotTmProject(geojson.NewFeatureCollection(), "HOT project", "123")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Importing empty task grid should fail as invalid request: %v", err))
+		}
+
+		return nil
+	})
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	h.Run(t, func() error {
+		_, err := tx.Exec("UPDATE projects SET needs_validation=true, assignment_timeout=12, josm_data_source='OVERPASS' WHERE id=1")
+		if err != nil {
+			return err
+		}
+
+		projectExport, err := s.ExportProject("1", "Peter")
+		if err != nil {
+			return err
+		}
+
+		if projectExport.SchemaVersion != CurrentSchemaVersion {
+			return errors.New(fmt.Sprintf("Export should have schema version %d but has %d", CurrentSchemaVersion, projectExport.SchemaVersion))
+		}
+		if len(projectExport.Tasks) != 1 || len(projectExport.Tasks[0].Comments) != 2 {
+			return errors.New("Export should contain the task with its two comments")
+		}
+
+		// Import by a different user, who becomes the new owner
+		result, err := s.ImportProject(projectExport, "Otto")
+		if err != nil {
+			return err
+		}
+
+		if result.Owner != "Otto" {
+			return errors.New(fmt.Sprintf("Importing user should be owner but owner is %s", result.Owner))
+		}
+		roles := make(map[string]permission.Role)
+		for _, m := range result.Members {
+			roles[m.UserId] = m.Role
+		}
+		if roles["Peter"] != permission.Moderator || roles["Maria"] != permission.Member {
+			return errors.New(fmt.Sprintf("Previous owner should be moderator and other members should keep their role: %v", roles))
+		}
+		if !result.NeedsValidation || result.AssignmentTimeout != 12 || result.JosmDataSource != project.Overpass {
+			return errors.New(fmt.Sprintf("Settings of project not imported: %#v", result))
+		}
+
+		if len(result.Tasks) != 1 || len(result.Tasks[0].Comments) != 2 {
+			return errors.New("Imported task should have two comments")
+		}
+		importedComment := result.Tasks[0].Comments[0]
+		originalComment := projectExport.Tasks[0].Comments[0]
+		if importedComment.Text != originalComment.Text || importedComment.AuthorId != originalComment.AuthorId || !importedComment.CreationDate.Equal(*originalComment.CreationDate) {
+			return errors.New(fmt.Sprintf("Comment not imported correctly: %#v", importedComment))
+		}
+
+		return nil
+	})
+}
+
+func TestImportProjectWithUnsupportedSchemaVersion(t *testing.T) {
+	h.Run(t, func() error {
+		projectExport := &ProjectExport{
+			SchemaVersion: CurrentSchemaVersion + 1,
+			Name:          "Test project",
+			Users:         []string{"123"},
+			Owner:         "123",
+			Tasks:         []*TaskExport{},
+		}
+
+		var requestError *util.InvalidRequestError
+		_, err := s.ImportProject(projectExport, "123")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Importing project of newer schema version should be an invalid request: %v", err))
+		}
 
 		return nil
 	})
@@ -0,0 +1,268 @@
+package geometry
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Rough length of one degree latitude in meters. This is precise enough to divide an area into tasks.
+	metersPerDegree = 111320.0
+)
+
+// BoundingBox is the extent of a geometry in WGS84 coordinates.
+type BoundingBox struct {
+	MinLon float64
+	MinLat float64
+	MaxLon float64
+	MaxLat float64
+}
+
+// ToPolygons turns a polygon or multi-polygon geometry into a list of polygons. Other geometry types are not supported
+// and will cause an error.
+func ToPolygons(geometry *geojson.Geometry) ([][][][]float64, error) {
+	if geometry == nil {
+		return nil, errors.New("geometry is null")
+	}
+
+	switch geometry.Type {
+	case geojson.GeometryPolygon:
+		return [][][][]float64{geometry.Polygon}, nil
+	case geojson.GeometryMultiPolygon:
+		return geometry.MultiPolygon, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("unsupported geometry type %s. Only \"%s\" and \"%s\" allowed", geometry.Type, geojson.GeometryPolygon, geojson.GeometryMultiPolygon))
+}
+
+// ParseAreaGeometry accepts a GeoJSON feature as well as a plain GeoJSON geometry. The geometry must be a polygon or a
+// multi-polygon, otherwise an InvalidGeometryError is returned.
+func ParseAreaGeometry(value string) (*geojson.Geometry, error) {
+	var geometry *geojson.Geometry
+
+	feature, err := geojson.UnmarshalFeature([]byte(value))
+	if err == nil && feature.Type == "Feature" {
+		geometry = feature.Geometry
+	} else {
+		geometry, err = geojson.UnmarshalGeometry([]byte(value))
+		if err != nil {
+			return nil, &InvalidGeometryError{errors.Wrap(err, "neither a GeoJSON feature nor a geometry")}
+		}
+	}
+
+	if geometry == nil || !(geometry.IsPolygon() || geometry.IsMultiPolygon()) {
+		return nil, &InvalidGeometryError{errors.New("geometry must be a polygon or multi-polygon")}
+	}
+
+	return geometry, nil
+}
+
+// GetBoundingBox determines the extent of all rings of all the given polygons.
+func GetBoundingBox(polygons [][][][]float64) BoundingBox {
+	bbox := BoundingBox{
+		MinLon: math.Inf(1),
+		MinLat: math.Inf(1),
+		MaxLon: math.Inf(-1),
+		MaxLat: math.Inf(-1),
+	}
+
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			for _, point := range ring {
+				bbox.MinLon = math.Min(bbox.MinLon, point[0])
+				bbox.MinLat = math.Min(bbox.MinLat, point[1])
+				bbox.MaxLon = math.Max(bbox.MaxLon, point[0])
+				bbox.MaxLat = math.Max(bbox.MaxLat, point[1])
+			}
+		}
+	}
+
+	return bbox
+}
+
+// ParseBoundingBox parses a bounding box of the form "minLon,minLat,maxLon,maxLat". Invalid values cause an
+// InvalidGeometryError.
+func ParseBoundingBox(value string) (*BoundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, &InvalidGeometryError{errors.New(fmt.Sprintf("bounding box '%s' must have the form 'minLon,minLat,maxLon,maxLat'", value))}
+	}
+
+	coordinates := make([]float64, len(parts))
+	for i, part := range parts {
+		coordinate, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(coordinate) {
+			return nil, &InvalidGeometryError{errors.New(fmt.Sprintf("bounding box '%s' contains invalid number '%s'", value, part))}
+		}
+		coordinates[i] = coordinate
+	}
+
+	bbox := &BoundingBox{
+		MinLon: coordinates[0],
+		MinLat: coordinates[1],
+		MaxLon: coordinates[2],
+		MaxLat: coordinates[3],
+	}
+
+	if bbox.MinLon < -180 || bbox.MaxLon > 180 || bbox.MinLat < -90 || bbox.MaxLat > 90 {
+		return nil, &InvalidGeometryError{errors.New(fmt.Sprintf("bounding box '%s' exceeds the WGS84 range", value))}
+	}
+	if bbox.MinLon > bbox.MaxLon || bbox.MinLat > bbox.MaxLat {
+		return nil, &InvalidGeometryError{errors.New(fmt.Sprintf("minimum of bounding box '%s' is larger than its maximum", value))}
+	}
+
+	return bbox, nil
+}
+
+// Intersects checks whether the polygon and one of the given mask polygons touch or overlap each other. Holes of the
+// polygons are respected.
+func Intersects(polygon [][][]float64, mask [][][][]float64) bool {
+	for _, maskPolygon := range mask {
+		if polygonsIntersect(polygon, maskPolygon) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains checks whether the point is within one of the given polygons. Points within holes are not contained.
+func Contains(polygons [][][][]float64, point []float64) bool {
+	for _, polygon := range polygons {
+		if pointInPolygon(point, polygon) {
+			return true
+		}
+	}
+	return false
+}
+
+func polygonsIntersect(a [][][]float64, b [][][]float64) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+
+	// One polygon lies (at least partially) within the other one
+	for _, point := range a[0] {
+		if pointInPolygon(point, b) {
+			return true
+		}
+	}
+	for _, point := range b[0] {
+		if pointInPolygon(point, a) {
+			return true
+		}
+	}
+
+	// The outlines cross each other without any vertex being inside the other polygon
+	for _, ringA := range a {
+		for _, ringB := range b {
+			if ringsIntersect(ringA, ringB) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// pointInPolygon uses the even-odd rule on all rings, so points within holes are not within the polygon.
+func pointInPolygon(point []float64, polygon [][][]float64) bool {
+	inside := false
+	for _, ring := range polygon {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			xi, yi := ring[i][0], ring[i][1]
+			xj, yj := ring[j][0], ring[j][1]
+
+			if (yi > point[1]) != (yj > point[1]) && point[0] < (xj-xi)*(point[1]-yi)/(yj-yi)+xi {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+func ringsIntersect(a [][]float64, b [][]float64) bool {
+	for i := 0; i < len(a)-1; i++ {
+		for j := 0; j < len(b)-1; j++ {
+			if segmentsIntersect(a[i], a[i+1], b[j], b[j+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentsIntersect checks if the segment p1-p2 touches or crosses the segment q1-q2.
+func segmentsIntersect(p1, p2, q1, q2 []float64) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+
+	// Collinear cases, where one end point lies on the other segment
+	return (d1 == 0 && onSegment(q1, q2, p1)) ||
+		(d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) ||
+		(d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// orientation is the cross product of the vectors a->b and a->c. It's positive when c is left of a->b and negative when
+// it's right of it.
+func orientation(a, b, c []float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// onSegment expects the point p to be collinear with a and b and checks if it's between them.
+func onSegment(a, b, p []float64) bool {
+	return math.Min(a[0], b[0]) <= p[0] && p[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= p[1] && p[1] <= math.Max(a[1], b[1])
+}
+
+// projection is a simple equirectangular projection around a center point. It turns WGS84 coordinates into local
+// coordinates in meters, which is good enough for areas the size of a typical project.
+type projection struct {
+	centerLon    float64
+	centerLat    float64
+	metersPerLon float64
+	metersPerLat float64
+}
+
+func newProjection(bbox BoundingBox) *projection {
+	centerLat := (bbox.MinLat + bbox.MaxLat) / 2
+	return &projection{
+		centerLon:    (bbox.MinLon + bbox.MaxLon) / 2,
+		centerLat:    centerLat,
+		metersPerLon: metersPerDegree * math.Cos(centerLat*math.Pi/180),
+		metersPerLat: metersPerDegree,
+	}
+}
+
+func (p *projection) toMeters(point []float64) []float64 {
+	return []float64{
+		(point[0] - p.centerLon) * p.metersPerLon,
+		(point[1] - p.centerLat) * p.metersPerLat,
+	}
+}
+
+func (p *projection) toDegrees(point []float64) []float64 {
+	return []float64{
+		point[0]/p.metersPerLon + p.centerLon,
+		point[1]/p.metersPerLat + p.centerLat,
+	}
+}
+
+func (p *projection) ringToDegrees(ring [][]float64) [][]float64 {
+	result := make([][]float64, len(ring))
+	for i, point := range ring {
+		result[i] = p.toDegrees(point)
+	}
+	return result
+}
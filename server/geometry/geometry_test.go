@@ -0,0 +1,29 @@
+package geometry
+
+import (
+	"testing"
+)
+
+func TestParseBoundingBox(t *testing.T) {
+	bbox, err := ParseBoundingBox("9.98, 53.55,9.995,53.559")
+	if err != nil {
+		t.Errorf("Valid bounding box should be parsed: %s", err.Error())
+		t.Fail()
+		return
+	}
+
+	if bbox.MinLon != 9.98 || bbox.MinLat != 53.55 || bbox.MaxLon != 9.995 || bbox.MaxLat != 53.559 {
+		t.Errorf("Wrong bounding box: %v", bbox)
+		t.Fail()
+		return
+	}
+
+	for _, value := range []string{"", "9.98,53.55,9.995", "9.98,53.55,9.995,abc", "9.98,53.55,190,53.559", "9.995,53.55,9.98,53.559"} {
+		_, err = ParseBoundingBox(value)
+		if err == nil {
+			t.Errorf("Invalid bounding box '%s' should not be parsed", value)
+			t.Fail()
+			return
+		}
+	}
+}
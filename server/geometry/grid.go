@@ -0,0 +1,333 @@
+package geometry
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/pkg/errors"
+)
+
+type DivisionMode string
+
+const (
+	SquareGrid   DivisionMode = "squareGrid"
+	HexGrid      DivisionMode = "hexGrid"
+	TriangleGrid DivisionMode = "triangleGrid"
+	Voronoi      DivisionMode = "voronoi"
+)
+
+const (
+	// Number of relaxation steps moving the voronoi seeds into the center of their cells. More steps result in more
+	// evenly sized cells.
+	voronoiRelaxationSteps = 5
+	// Maximum attempts per seed to find a random point within the area before giving up.
+	voronoiSeedAttempts = 1000
+)
+
+// EstimateCellCount returns the amount of cells created for the bounding box of the given polygons. This is an upper
+// bound of the number of cells created by Divide and can be used to reject requests that would create way too many
+// cells before actually creating them.
+func EstimateCellCount(polygons [][][][]float64, mode DivisionMode, cellSize float64, cellCount int) int {
+	if mode == Voronoi {
+		return cellCount
+	}
+	if !(cellSize > 0) {
+		return 0
+	}
+
+	bbox := GetBoundingBox(polygons)
+	p := newProjection(bbox)
+	min := p.toMeters([]float64{bbox.MinLon, bbox.MinLat})
+	max := p.toMeters([]float64{bbox.MaxLon, bbox.MaxLat})
+	area := (max[0] - min[0] + cellSize) * (max[1] - min[1] + cellSize)
+
+	var cellArea float64
+	switch mode {
+	case SquareGrid:
+		cellArea = cellSize * cellSize
+	case HexGrid:
+		cellArea = 3 * math.Sqrt(3) / 2 * cellSize * cellSize
+	case TriangleGrid:
+		cellArea = cellSize * cellSize / 2
+	default:
+		return 0
+	}
+
+	// Prevent overflows for extremely small cells
+	return int(math.Min(math.Ceil(area/cellArea), math.MaxInt32))
+}
+
+// Divide creates a grid of the given mode for the area of the polygons and returns all cells touching the area. The
+// cell size is given in meters and only used for the grid modes. The cell count is only used for the voronoi mode.
+func Divide(polygons [][][][]float64, mode DivisionMode, cellSize float64, cellCount int) ([][][][]float64, error) {
+	if len(polygons) == 0 {
+		return nil, errors.New("no area to divide given")
+	}
+
+	bbox := GetBoundingBox(polygons)
+	p := newProjection(bbox)
+
+	// All calculations are done in meters to get cells with the same size in every direction
+	mask := make([][][][]float64, len(polygons))
+	for i, polygon := range polygons {
+		mask[i] = make([][][]float64, len(polygon))
+		for j, ring := range polygon {
+			mask[i][j] = make([][]float64, len(ring))
+			for k, point := range ring {
+				mask[i][j][k] = p.toMeters(point)
+			}
+		}
+	}
+	min := p.toMeters([]float64{bbox.MinLon, bbox.MinLat})
+	max := p.toMeters([]float64{bbox.MaxLon, bbox.MaxLat})
+
+	if mode != Voronoi && !(cellSize > 0) {
+		return nil, errors.New(fmt.Sprintf("cell size must be larger than 0 (%f)", cellSize))
+	}
+
+	var cells [][][]float64
+	var err error
+	switch mode {
+	case SquareGrid:
+		cells = createSquareGrid(min, max, cellSize)
+	case HexGrid:
+		cells = createHexGrid(min, max, cellSize)
+	case TriangleGrid:
+		cells = createTriangleGrid(min, max, cellSize)
+	case Voronoi:
+		cells, err = createVoronoiCells(mask, min, max, cellCount)
+	default:
+		err = errors.New(fmt.Sprintf("unknown division mode '%s'", mode))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][][][]float64, 0)
+	for _, cell := range cells {
+		polygon := [][][]float64{cell}
+		if Intersects(polygon, mask) {
+			result = append(result, [][][]float64{p.ringToDegrees(cell)})
+		}
+	}
+
+	return result, nil
+}
+
+// createSquareGrid creates a grid of closed squares centered on the given extent. All rings are counterclockwise.
+func createSquareGrid(min []float64, max []float64, cellSize float64) [][][]float64 {
+	columns := int(math.Ceil((max[0] - min[0]) / cellSize))
+	rows := int(math.Ceil((max[1] - min[1]) / cellSize))
+	columns = int(math.Max(float64(columns), 1))
+	rows = int(math.Max(float64(rows), 1))
+
+	// Center the grid on the extent just like the client does it
+	offsetX := min[0] - (float64(columns)*cellSize-(max[0]-min[0]))/2
+	offsetY := min[1] - (float64(rows)*cellSize-(max[1]-min[1]))/2
+
+	cells := make([][][]float64, 0, columns*rows)
+	for column := 0; column < columns; column++ {
+		for row := 0; row < rows; row++ {
+			x := offsetX + float64(column)*cellSize
+			y := offsetY + float64(row)*cellSize
+			cells = append(cells, [][]float64{
+				{x, y},
+				{x + cellSize, y},
+				{x + cellSize, y + cellSize},
+				{x, y + cellSize},
+				{x, y},
+			})
+		}
+	}
+
+	return cells
+}
+
+// createHexGrid creates flat-topped hexagons covering the given extent. The cell size is the length of one side of the
+// hexagon, which is also the radius of its circumscribed circle.
+func createHexGrid(min []float64, max []float64, cellSize float64) [][][]float64 {
+	horizontalStep := 1.5 * cellSize
+	verticalStep := math.Sqrt(3) * cellSize
+
+	columns := int(math.Ceil((max[0]-min[0])/horizontalStep)) + 1
+	rows := int(math.Ceil((max[1]-min[1])/verticalStep)) + 1
+
+	cells := make([][][]float64, 0, columns*rows)
+	for column := 0; column < columns; column++ {
+		for row := 0; row < rows; row++ {
+			centerX := min[0] + float64(column)*horizontalStep
+			centerY := min[1] + float64(row)*verticalStep
+			if column%2 == 1 {
+				centerY += verticalStep / 2
+			}
+
+			ring := make([][]float64, 7)
+			for i := 0; i < 6; i++ {
+				angle := float64(i) * math.Pi / 3
+				ring[i] = []float64{centerX + cellSize*math.Cos(angle), centerY + cellSize*math.Sin(angle)}
+			}
+			ring[6] = ring[0]
+
+			cells = append(cells, ring)
+		}
+	}
+
+	return cells
+}
+
+// createTriangleGrid divides each square of a square grid into two triangles. The diagonal alternates between
+// neighboring squares.
+func createTriangleGrid(min []float64, max []float64, cellSize float64) [][][]float64 {
+	squares := createSquareGrid(min, max, cellSize)
+
+	cells := make([][][]float64, 0, 2*len(squares))
+	for i, square := range squares {
+		lowerLeft, lowerRight, upperRight, upperLeft := square[0], square[1], square[2], square[3]
+
+		if i%2 == 0 {
+			cells = append(cells,
+				[][]float64{lowerLeft, lowerRight, upperRight, lowerLeft},
+				[][]float64{lowerLeft, upperRight, upperLeft, lowerLeft},
+			)
+		} else {
+			cells = append(cells,
+				[][]float64{lowerLeft, lowerRight, upperLeft, lowerLeft},
+				[][]float64{lowerRight, upperRight, upperLeft, lowerRight},
+			)
+		}
+	}
+
+	return cells
+}
+
+// createVoronoiCells places the given amount of seeds within the mask and creates the voronoi cells of them clipped to
+// the given extent. The seeds are placed randomly but deterministic, so the same input always creates the same cells.
+func createVoronoiCells(mask [][][][]float64, min []float64, max []float64, cellCount int) ([][][]float64, error) {
+	if cellCount < 1 {
+		return nil, errors.New(fmt.Sprintf("cell count must be at least 1 (%d)", cellCount))
+	}
+
+	random := rand.New(rand.NewSource(int64(cellCount)))
+	seeds := make([][]float64, 0, cellCount)
+	for attempts := 0; len(seeds) < cellCount; attempts++ {
+		if attempts > cellCount*voronoiSeedAttempts {
+			return nil, errors.New(fmt.Sprintf("unable to place %d seeds within the area", cellCount))
+		}
+
+		seed := []float64{
+			min[0] + random.Float64()*(max[0]-min[0]),
+			min[1] + random.Float64()*(max[1]-min[1]),
+		}
+		if Contains(mask, seed) {
+			seeds = append(seeds, seed)
+		}
+	}
+
+	extent := [][]float64{
+		{min[0], min[1]},
+		{max[0], min[1]},
+		{max[0], max[1]},
+		{min[0], max[1]},
+	}
+
+	cells := make([][][]float64, len(seeds))
+	for step := 0; step <= voronoiRelaxationSteps; step++ {
+		for i := range seeds {
+			cells[i] = createVoronoiCell(extent, seeds, i)
+		}
+
+		if step == voronoiRelaxationSteps {
+			break
+		}
+
+		// Lloyd relaxation: Move each seed into the centroid of its cell, as long as it stays within the area
+		for i, cell := range cells {
+			centroid := getCentroid(cell)
+			if centroid != nil && Contains(mask, centroid) {
+				seeds[i] = centroid
+			}
+		}
+	}
+
+	// Close all rings
+	for i, cell := range cells {
+		cells[i] = append(cell, cell[0])
+	}
+
+	return cells, nil
+}
+
+// createVoronoiCell clips the extent by the bisector between the i-th seed and every other seed. The resulting ring is
+// not closed.
+func createVoronoiCell(extent [][]float64, seeds [][]float64, i int) [][]float64 {
+	cell := extent
+	seed := seeds[i]
+
+	for j, other := range seeds {
+		if i == j || len(cell) == 0 {
+			continue
+		}
+
+		// Keep everything that's closer to the seed than to the other seed
+		normal := []float64{other[0] - seed[0], other[1] - seed[1]}
+		midpoint := []float64{(seed[0] + other[0]) / 2, (seed[1] + other[1]) / 2}
+		distance := func(point []float64) float64 {
+			return (point[0]-midpoint[0])*normal[0] + (point[1]-midpoint[1])*normal[1]
+		}
+
+		cell = clipByHalfPlane(cell, distance)
+	}
+
+	return cell
+}
+
+// clipByHalfPlane removes all parts of the convex, unclosed ring, which have a positive distance. This is the
+// Sutherland-Hodgman algorithm for one clipping edge.
+func clipByHalfPlane(ring [][]float64, distance func([]float64) float64) [][]float64 {
+	result := make([][]float64, 0, len(ring)+1)
+
+	for i := range ring {
+		current := ring[i]
+		next := ring[(i+1)%len(ring)]
+		currentDistance := distance(current)
+		nextDistance := distance(next)
+
+		if currentDistance <= 0 {
+			result = append(result, current)
+		}
+
+		if (currentDistance < 0 && nextDistance > 0) || (currentDistance > 0 && nextDistance < 0) {
+			t := currentDistance / (currentDistance - nextDistance)
+			result = append(result, []float64{
+				current[0] + t*(next[0]-current[0]),
+				current[1] + t*(next[1]-current[1]),
+			})
+		}
+	}
+
+	return result
+}
+
+// getCentroid calculates the centroid of the unclosed ring. Degenerated rings without area have no centroid.
+func getCentroid(ring [][]float64) []float64 {
+	area := 0.0
+	x := 0.0
+	y := 0.0
+
+	for i := range ring {
+		current := ring[i]
+		next := ring[(i+1)%len(ring)]
+		cross := current[0]*next[1] - next[0]*current[1]
+
+		area += cross
+		x += (current[0] + next[0]) * cross
+		y += (current[1] + next[1]) * cross
+	}
+
+	if area == 0 {
+		return nil
+	}
+
+	return []float64{x / (3 * area), y / (3 * area)}
+}
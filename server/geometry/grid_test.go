@@ -0,0 +1,156 @@
+package geometry
+
+import (
+	"testing"
+)
+
+// Roughly 1km x 1km square in Hamburg
+var square = [][][][]float64{{{
+	{9.98, 53.55},
+	{9.995, 53.55},
+	{9.995, 53.559},
+	{9.98, 53.559},
+	{9.98, 53.55},
+}}}
+
+func TestDivideSquareGrid(t *testing.T) {
+	cells, err := Divide(square, SquareGrid, 500, 0)
+	if err != nil {
+		t.Errorf("Dividing should work: %s", err.Error())
+		t.Fail()
+		return
+	}
+
+	// 1km in each direction results in 2x2 cells or, due to rounding, 3x3 cells
+	if len(cells) < 4 || len(cells) > 9 {
+		t.Errorf("Unexpected number of cells: %d", len(cells))
+		t.Fail()
+		return
+	}
+
+	for _, cell := range cells {
+		ring := cell[0]
+		if len(ring) != 5 || ring[0][0] != ring[4][0] || ring[0][1] != ring[4][1] {
+			t.Errorf("Cell ring is not a closed square: %v", ring)
+			t.Fail()
+			return
+		}
+	}
+}
+
+func TestDivideHexAndTriangleGrid(t *testing.T) {
+	cells, err := Divide(square, HexGrid, 300, 0)
+	if err != nil {
+		t.Errorf("Dividing into hexagons should work: %s", err.Error())
+		t.Fail()
+		return
+	}
+	if len(cells) == 0 || len(cells[0][0]) != 7 {
+		t.Errorf("Expected closed hexagons but got %v", cells)
+		t.Fail()
+		return
+	}
+
+	cells, err = Divide(square, TriangleGrid, 500, 0)
+	if err != nil {
+		t.Errorf("Dividing into triangles should work: %s", err.Error())
+		t.Fail()
+		return
+	}
+	if len(cells) == 0 || len(cells[0][0]) != 4 {
+		t.Errorf("Expected closed triangles but got %v", cells)
+		t.Fail()
+		return
+	}
+}
+
+func TestDivideVoronoi(t *testing.T) {
+	cells, err := Divide(square, Voronoi, 0, 12)
+	if err != nil {
+		t.Errorf("Dividing into voronoi cells should work: %s", err.Error())
+		t.Fail()
+		return
+	}
+
+	if len(cells) != 12 {
+		t.Errorf("Expected 12 cells but got %d", len(cells))
+		t.Fail()
+		return
+	}
+
+	// Same input, same output
+	otherCells, err := Divide(square, Voronoi, 0, 12)
+	if err != nil || otherCells[3][0][0][0] != cells[3][0][0][0] {
+		t.Errorf("Voronoi cells should be deterministic")
+		t.Fail()
+		return
+	}
+}
+
+func TestDivideOnlyReturnsCellsWithinArea(t *testing.T) {
+	// L-shaped area, the upper right quarter of the bounding box is empty
+	area := [][][][]float64{{{
+		{9.98, 53.55},
+		{9.995, 53.55},
+		{9.995, 53.5545},
+		{9.9875, 53.5545},
+		{9.9875, 53.559},
+		{9.98, 53.559},
+		{9.98, 53.55},
+	}}}
+
+	cells, err := Divide(area, SquareGrid, 200, 0)
+	if err != nil {
+		t.Errorf("Dividing should work: %s", err.Error())
+		t.Fail()
+		return
+	}
+
+	for _, cell := range cells {
+		lowerLeft := cell[0][0]
+		if lowerLeft[0] > 9.989 && lowerLeft[1] > 53.556 {
+			t.Errorf("Cell %v is outside of the area", cell)
+			t.Fail()
+			return
+		}
+	}
+}
+
+func TestDivideInvalidParameters(t *testing.T) {
+	_, err := Divide(square, SquareGrid, 0, 0)
+	if err == nil {
+		t.Error("Cell size of 0 should not be allowed")
+		t.Fail()
+		return
+	}
+
+	_, err = Divide(square, Voronoi, 0, 0)
+	if err == nil {
+		t.Error("Cell count of 0 should not be allowed")
+		t.Fail()
+		return
+	}
+
+	_, err = Divide(square, "circleGrid", 100, 0)
+	if err == nil {
+		t.Error("Unknown modes should not be allowed")
+		t.Fail()
+		return
+	}
+}
+
+func TestEstimateCellCount(t *testing.T) {
+	estimation := EstimateCellCount(square, SquareGrid, 100, 0)
+	if estimation < 100 || estimation > 150 {
+		t.Errorf("Estimation of %d is not plausible", estimation)
+		t.Fail()
+		return
+	}
+
+	estimation = EstimateCellCount(square, Voronoi, 0, 42)
+	if estimation != 42 {
+		t.Errorf("Estimation for voronoi cells should be the cell count but was %d", estimation)
+		t.Fail()
+		return
+	}
+}
@@ -0,0 +1,262 @@
+package geometry
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+)
+
+// Maximum number of points of one ring. The validation of larger rings would be too expensive.
+const maxRingPoints = 10000
+
+// InvalidGeometryError is caused by a geometry given by the user, which is invalid and can't be repaired.
+type InvalidGeometryError struct {
+	Err error
+}
+
+func (e *InvalidGeometryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidGeometryError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateGeometry checks and repairs the polygon or multi-polygon geometry just like ValidatePolygons does. The
+// returned geometry is a repaired copy or the given geometry, when nothing had to be repaired. Problems are returned
+// as InvalidGeometryError.
+func ValidateGeometry(geometry *geojson.Geometry, minArea float64, maxArea float64) (*geojson.Geometry, bool, error) {
+	polygons, err := ToPolygons(geometry)
+	if err != nil {
+		return nil, false, &InvalidGeometryError{err}
+	}
+
+	repairedPolygons, repaired, err := ValidatePolygons(polygons, minArea, maxArea)
+	if err != nil {
+		return nil, false, &InvalidGeometryError{err}
+	}
+
+	if !repaired {
+		return geometry, false, nil
+	}
+
+	if geometry.Type == geojson.GeometryPolygon {
+		return geojson.NewPolygonGeometry(repairedPolygons[0]), true, nil
+	}
+	return geojson.NewMultiPolygonGeometry(repairedPolygons...), true, nil
+}
+
+// ValidatePolygons checks the polygons of a task and repairs small problems: Duplicate consecutive vertices and
+// additional coordinates (like the altitude) are removed, unclosed rings are closed and the winding order is corrected
+// (counterclockwise outer rings and clockwise holes, as required by RFC 7946). Problems that can't be repaired, like
+// self-intersecting rings or holes outside the outer ring, result in an error.
+// The area of all polygons (in square meters) must be within the given limits, a maximum of 0 means there's no maximum.
+// The returned flag is true when the polygons have been repaired.
+func ValidatePolygons(polygons [][][][]float64, minArea float64, maxArea float64) ([][][][]float64, bool, error) {
+	if len(polygons) == 0 {
+		return nil, false, errors.New("geometry contains no polygons")
+	}
+
+	repaired := false
+	result := make([][][][]float64, len(polygons))
+
+	for p, polygon := range polygons {
+		if len(polygon) == 0 {
+			return nil, false, errors.New(fmt.Sprintf("polygon %d has no rings", p))
+		}
+
+		result[p] = make([][][]float64, len(polygon))
+		for r, ring := range polygon {
+			if len(ring) > maxRingPoints {
+				return nil, false, errors.New(fmt.Sprintf("ring %d of polygon %d has more than %d points", r, p, maxRingPoints))
+			}
+
+			err := checkCoordinates(ring)
+			if err != nil {
+				return nil, false, errors.Wrapf(err, "ring %d of polygon %d is invalid", r, p)
+			}
+
+			cleanRing, ringRepaired := cleanUpRing(ring)
+			repaired = repaired || ringRepaired
+
+			// A closed ring needs at least three different points and the closing point
+			if len(cleanRing) < 4 {
+				return nil, false, errors.New(fmt.Sprintf("ring %d of polygon %d has less than three different points", r, p))
+			}
+
+			if ringSelfIntersects(cleanRing) {
+				return nil, false, errors.New(fmt.Sprintf("ring %d of polygon %d intersects itself", r, p))
+			}
+
+			signedArea := getSignedArea(cleanRing)
+			if signedArea == 0 {
+				return nil, false, errors.New(fmt.Sprintf("ring %d of polygon %d has no area", r, p))
+			}
+
+			// Outer rings must be counterclockwise (positive area), holes clockwise (negative area)
+			isOuterRing := r == 0
+			if isOuterRing != (signedArea > 0) {
+				cleanRing = reverseRing(cleanRing)
+				repaired = true
+			}
+
+			result[p][r] = cleanRing
+		}
+
+		err := checkHoles(result[p])
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "polygon %d is invalid", p)
+		}
+	}
+
+	area := GetArea(result)
+	if area < minArea {
+		return nil, false, errors.New(fmt.Sprintf("area of %.0fm² is smaller than the minimum of %.0fm²", area, minArea))
+	}
+	if maxArea > 0 && area > maxArea {
+		return nil, false, errors.New(fmt.Sprintf("area of %.0fm² is larger than the maximum of %.0fm²", area, maxArea))
+	}
+
+	return result, repaired, nil
+}
+
+// GetArea determines the area of the polygons in square meters. The area of holes is subtracted.
+func GetArea(polygons [][][][]float64) float64 {
+	proj := newProjection(GetBoundingBox(polygons))
+
+	area := 0.0
+	for _, polygon := range polygons {
+		for r, ring := range polygon {
+			projectedRing := make([][]float64, len(ring))
+			for i, point := range ring {
+				projectedRing[i] = proj.toMeters(point)
+			}
+
+			ringArea := math.Abs(getSignedArea(projectedRing))
+			if r == 0 {
+				area += ringArea
+			} else {
+				area -= ringArea
+			}
+		}
+	}
+
+	return area
+}
+
+func checkCoordinates(ring [][]float64) error {
+	for i, point := range ring {
+		if len(point) < 2 {
+			return errors.New(fmt.Sprintf("point %d has less than two coordinates", i))
+		}
+		if math.IsNaN(point[0]) || math.IsNaN(point[1]) || point[0] < -180 || point[0] > 180 || point[1] < -90 || point[1] > 90 {
+			return errors.New(fmt.Sprintf("point %d has invalid coordinates %v", i, point))
+		}
+	}
+	return nil
+}
+
+// cleanUpRing removes duplicate consecutive points as well as additional coordinates of the points and closes the ring
+// if necessary. The returned flag is true when the ring has been changed.
+func cleanUpRing(ring [][]float64) ([][]float64, bool) {
+	changed := false
+
+	result := make([][]float64, 0, len(ring)+1)
+	for _, point := range ring {
+		if len(point) > 2 {
+			changed = true
+		}
+		if len(result) > 0 && pointsEqual(result[len(result)-1], point) {
+			changed = true
+			continue
+		}
+		result = append(result, point[:2])
+	}
+
+	if len(result) > 0 && !pointsEqual(result[0], result[len(result)-1]) {
+		result = append(result, result[0])
+		changed = true
+	}
+
+	return result, changed
+}
+
+// ringSelfIntersects checks all pairs of non-adjacent segments of the closed ring for intersections. The segments are
+// sorted by their smallest x coordinate, so that only segments overlapping in x direction are compared.
+func ringSelfIntersects(ring [][]float64) bool {
+	numberOfSegments := len(ring) - 1
+
+	segments := make([]int, numberOfSegments)
+	for i := range segments {
+		segments[i] = i
+	}
+	minX := func(i int) float64 { return math.Min(ring[i][0], ring[i+1][0]) }
+	maxX := func(i int) float64 { return math.Max(ring[i][0], ring[i+1][0]) }
+	sort.Slice(segments, func(a, b int) bool { return minX(segments[a]) < minX(segments[b]) })
+
+	for a := 0; a < numberOfSegments; a++ {
+		i := segments[a]
+		for b := a + 1; b < numberOfSegments && minX(segments[b]) <= maxX(i); b++ {
+			j := segments[b]
+
+			// Adjacent segments share a point, this includes the first and last segment sharing the closing point
+			if j == i+1 || i == j+1 || (i == 0 && j == numberOfSegments-1) || (j == 0 && i == numberOfSegments-1) {
+				continue
+			}
+
+			if segmentsIntersect(ring[i], ring[i+1], ring[j], ring[j+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkHoles makes sure that all holes of the polygon are within the outer ring and don't overlap each other. Holes
+// must not touch the outer ring or other holes.
+func checkHoles(polygon [][][]float64) error {
+	outerRing := polygon[0]
+	for h := 1; h < len(polygon); h++ {
+		hole := polygon[h]
+
+		if ringsIntersect(hole, outerRing) || !pointInPolygon(hole[0], [][][]float64{outerRing}) {
+			return errors.New(fmt.Sprintf("hole %d is not within the outer ring", h))
+		}
+
+		for other := 1; other < h; other++ {
+			otherHole := polygon[other]
+			if ringsIntersect(hole, otherHole) ||
+				pointInPolygon(hole[0], [][][]float64{otherHole}) ||
+				pointInPolygon(otherHole[0], [][][]float64{hole}) {
+				return errors.New(fmt.Sprintf("hole %d overlaps hole %d", h, other))
+			}
+		}
+	}
+
+	return nil
+}
+
+// getSignedArea uses the shoelace formula on the closed ring. The result is positive for counterclockwise rings and
+// negative for clockwise rings. The unit depends on the coordinates of the ring.
+func getSignedArea(ring [][]float64) float64 {
+	sum := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		sum += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return sum / 2
+}
+
+func reverseRing(ring [][]float64) [][]float64 {
+	result := make([][]float64, len(ring))
+	for i, point := range ring {
+		result[len(ring)-1-i] = point
+	}
+	return result
+}
+
+func pointsEqual(a []float64, b []float64) bool {
+	return a[0] == b[0] && a[1] == b[1]
+}
@@ -0,0 +1,247 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValidatePolygons(t *testing.T) {
+	polygons, repaired, err := ValidatePolygons(square, 0, 0)
+	if err != nil {
+		t.Errorf("Valid square should pass: %s", err.Error())
+		t.Fail()
+		return
+	}
+
+	if repaired || len(polygons[0][0]) != 5 {
+		t.Errorf("Valid square should not be repaired: %v", polygons)
+		t.Fail()
+		return
+	}
+}
+
+func TestValidatePolygonsRepairsRings(t *testing.T) {
+	// Clockwise, not closed and with duplicate vertex
+	polygon := [][][][]float64{{{
+		{9.98, 53.55},
+		{9.98, 53.559},
+		{9.98, 53.559},
+		{9.995, 53.559},
+		{9.995, 53.55},
+	}}}
+
+	polygons, repaired, err := ValidatePolygons(polygon, 0, 0)
+	if err != nil {
+		t.Errorf("Repairable polygon should pass: %s", err.Error())
+		t.Fail()
+		return
+	}
+
+	ring := polygons[0][0]
+	if !repaired || len(ring) != 5 {
+		t.Errorf("Ring should be repaired and contain five points: %v", ring)
+		t.Fail()
+		return
+	}
+	if !pointsEqual(ring[0], ring[4]) {
+		t.Errorf("Ring should be closed: %v", ring)
+		t.Fail()
+		return
+	}
+	if getSignedArea(ring) <= 0 {
+		t.Errorf("Outer ring should be counterclockwise: %v", ring)
+		t.Fail()
+		return
+	}
+}
+
+func TestValidatePolygonsRepairsRingsOfSameLength(t *testing.T) {
+	// Counterclockwise but not closed and with duplicate vertex, so the repaired ring has the same length
+	polygon := [][][][]float64{{{
+		{9.98, 53.55},
+		{9.995, 53.55},
+		{9.995, 53.55},
+		{9.995, 53.559},
+		{9.98, 53.559},
+	}}}
+
+	polygons, repaired, err := ValidatePolygons(polygon, 0, 0)
+	if err != nil {
+		t.Errorf("Repairable polygon should pass: %s", err.Error())
+		t.Fail()
+		return
+	}
+
+	ring := polygons[0][0]
+	if !repaired || !pointsEqual(ring[0], ring[len(ring)-1]) {
+		t.Errorf("Ring should be repaired and closed: %v", ring)
+		t.Fail()
+		return
+	}
+
+	// Altitudes are removed
+	polygon = [][][][]float64{{{
+		{9.98, 53.55, 10},
+		{9.995, 53.55, 10},
+		{9.995, 53.559, 10},
+		{9.98, 53.559, 10},
+		{9.98, 53.55, 10},
+	}}}
+
+	polygons, repaired, err = ValidatePolygons(polygon, 0, 0)
+	if err != nil {
+		t.Errorf("Polygon with altitudes should pass: %s", err.Error())
+		t.Fail()
+		return
+	}
+	if !repaired || len(polygons[0][0][0]) != 2 {
+		t.Errorf("Altitudes should be removed: %v", polygons)
+		t.Fail()
+		return
+	}
+}
+
+func TestValidatePolygonsRepairsHoles(t *testing.T) {
+	// Counterclockwise hole
+	polygon := [][][][]float64{{
+		square[0][0],
+		{{9.985, 53.552}, {9.99, 53.552}, {9.99, 53.556}, {9.985, 53.552}},
+	}}
+
+	polygons, repaired, err := ValidatePolygons(polygon, 0, 0)
+	if err != nil {
+		t.Errorf("Polygon with hole should pass: %s", err.Error())
+		t.Fail()
+		return
+	}
+
+	if !repaired || getSignedArea(polygons[0][1]) >= 0 {
+		t.Errorf("Hole should be clockwise: %v", polygons[0][1])
+		t.Fail()
+		return
+	}
+}
+
+func TestValidatePolygonsRejectsInvalidRings(t *testing.T) {
+	// Bow tie
+	selfIntersecting := [][][][]float64{{{
+		{9.98, 53.55},
+		{9.995, 53.559},
+		{9.995, 53.55},
+		{9.98, 53.559},
+		{9.98, 53.55},
+	}}}
+	_, _, err := ValidatePolygons(selfIntersecting, 0, 0)
+	if err == nil {
+		t.Error("Self-intersecting ring should not be allowed")
+		t.Fail()
+		return
+	}
+
+	tooFewPoints := [][][][]float64{{{
+		{9.98, 53.55},
+		{9.995, 53.559},
+		{9.98, 53.55},
+	}}}
+	_, _, err = ValidatePolygons(tooFewPoints, 0, 0)
+	if err == nil {
+		t.Error("Ring with two different points should not be allowed")
+		t.Fail()
+		return
+	}
+
+	collinear := [][][][]float64{{{
+		{9.98, 53.55},
+		{9.99, 53.55},
+		{9.995, 53.55},
+		{9.98, 53.55},
+	}}}
+	_, _, err = ValidatePolygons(collinear, 0, 0)
+	if err == nil {
+		t.Error("Ring without area should not be allowed")
+		t.Fail()
+		return
+	}
+
+	holeOutside := [][][][]float64{{
+		square[0][0],
+		{{10.1, 53.552}, {10.1, 53.556}, {10.2, 53.556}, {10.1, 53.552}},
+	}}
+	_, _, err = ValidatePolygons(holeOutside, 0, 0)
+	if err == nil {
+		t.Error("Hole outside of the outer ring should not be allowed")
+		t.Fail()
+		return
+	}
+
+	overlappingHoles := [][][][]float64{{
+		square[0][0],
+		{{9.985, 53.552}, {9.985, 53.556}, {9.99, 53.556}, {9.985, 53.552}},
+		{{9.986, 53.552}, {9.986, 53.556}, {9.991, 53.556}, {9.986, 53.552}},
+	}}
+	_, _, err = ValidatePolygons(overlappingHoles, 0, 0)
+	if err == nil {
+		t.Error("Overlapping holes should not be allowed")
+		t.Fail()
+		return
+	}
+
+	tooManyPoints := make([][]float64, maxRingPoints+1)
+	for i := range tooManyPoints {
+		angle := 2 * math.Pi * float64(i) / float64(maxRingPoints)
+		tooManyPoints[i] = []float64{9.98 + 0.01*math.Cos(angle), 53.55 + 0.01*math.Sin(angle)}
+	}
+	_, _, err = ValidatePolygons([][][][]float64{{tooManyPoints}}, 0, 0)
+	if err == nil {
+		t.Error("Ring with too many points should not be allowed")
+		t.Fail()
+		return
+	}
+
+	invalidCoordinates := [][][][]float64{{{
+		{9.98, 53.55},
+		{190, 53.55},
+		{190, 95},
+		{9.98, 53.55},
+	}}}
+	_, _, err = ValidatePolygons(invalidCoordinates, 0, 0)
+	if err == nil {
+		t.Error("Coordinates outside of the WGS84 range should not be allowed")
+		t.Fail()
+		return
+	}
+}
+
+func TestValidatePolygonsArea(t *testing.T) {
+	// The square has an area of roughly 1km²
+	_, _, err := ValidatePolygons(square, 2000000, 0)
+	if err == nil {
+		t.Error("Area smaller than the minimum should not be allowed")
+		t.Fail()
+		return
+	}
+
+	_, _, err = ValidatePolygons(square, 0, 500000)
+	if err == nil {
+		t.Error("Area larger than the maximum should not be allowed")
+		t.Fail()
+		return
+	}
+
+	_, _, err = ValidatePolygons(square, 500000, 2000000)
+	if err != nil {
+		t.Errorf("Area within the limits should be allowed: %s", err.Error())
+		t.Fail()
+		return
+	}
+}
+
+func TestGetArea(t *testing.T) {
+	// 0.015° longitude and 0.009° latitude in Hamburg are roughly 993m x 1002m
+	area := GetArea(square)
+	if math.Abs(area-995000) > 10000 {
+		t.Errorf("Area of %f is not plausible", area)
+		t.Fail()
+		return
+	}
+}
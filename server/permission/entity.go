@@ -0,0 +1,34 @@
+package permission
+
+type Role string
+
+const (
+	Owner     Role = "OWNER"     // Can do everything. There's exactly one owner per project.
+	Moderator Role = "MODERATOR" // Can add and remove users and edit the project and its tasks but can't delete the project.
+	Member    Role = "MEMBER"    // Can work on tasks.
+	Viewer    Role = "VIEWER"    // Can only see the project but can't change anything.
+)
+
+var (
+	moderatingRoles = []string{string(Owner), string(Moderator)}
+	writingRoles    = []string{string(Owner), string(Moderator), string(Member)}
+)
+
+// IsValidRole returns true for all known roles.
+func IsValidRole(role Role) bool {
+	return role == Owner || role == Moderator || role == Member || role == Viewer
+}
+
+// PermissionError is caused by a user who is not allowed to perform an action, e.g. because the user is no member of
+// the project or doesn't have the needed role.
+type PermissionError struct {
+	Err error
+}
+
+func (e *PermissionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
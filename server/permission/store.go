@@ -16,6 +16,7 @@ type Store struct {
 var (
 	taskTable    = "tasks"
 	projectTable = "projects"
+	memberTable  = "project_members"
 )
 
 // Init the permission store for the project and task table.
@@ -28,10 +29,10 @@ func Init(tx *sql.Tx, logger *util.Logger) *Store {
 
 // VerifyOwnership check if the given user is the owner of the given project.
 func (s *Store) VerifyOwnership(projectId string, user string) error {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id=$1 AND owner=$2", projectTable)
+	query := fmt.Sprintf("SELECT * FROM %s WHERE project_id=$1 AND user_id=$2 AND role=$3", memberTable)
 
-	s.LogQuery(query, projectId, user)
-	rows, err := s.tx.Query(query, projectId, user)
+	s.LogQuery(query, projectId, user, Owner)
+	rows, err := s.tx.Query(query, projectId, user, Owner)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("error verifying ownership of user %s in project %s", user, projectId))
 	}
@@ -39,15 +40,33 @@ func (s *Store) VerifyOwnership(projectId string, user string) error {
 
 	// If there's a next row, then the user "user" is in the owner of the project "projectId"
 	if !rows.Next() {
-		return errors.New(fmt.Sprintf("user %s is not the owner of project %s", user, projectId))
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is not the owner of project %s", user, projectId))}
+	}
+
+	return nil
+}
+
+// VerifyModeration checks if the given user is the owner or a moderator of the given project.
+func (s *Store) VerifyModeration(projectId string, user string) error {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE project_id=$1 AND user_id=$2 AND role=ANY($3)", memberTable)
+
+	s.LogQuery(query, projectId, user, moderatingRoles)
+	rows, err := s.tx.Query(query, projectId, user, pq.Array(moderatingRoles))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("error verifying moderation rights of user %s in project %s", user, projectId))
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is neither owner nor moderator of project %s", user, projectId))}
 	}
 
 	return nil
 }
 
-// VerifyMembershipProject checks if "user" is a member of the project "id".
+// VerifyMembershipProject checks if "user" is a member of the project "id". Every role counts as membership.
 func (s *Store) VerifyMembershipProject(projectId string, user string) error {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id=$1 AND $2=ANY(users)", projectTable)
+	query := fmt.Sprintf("SELECT * FROM %s WHERE project_id=$1 AND user_id=$2", memberTable)
 
 	s.LogQuery(query, projectId, user)
 	rows, err := s.tx.Query(query, projectId, user)
@@ -58,7 +77,26 @@ func (s *Store) VerifyMembershipProject(projectId string, user string) error {
 
 	// If there's a next row, then the user "user" is in the list of members of project "projectId"
 	if !rows.Next() {
-		return errors.New(fmt.Sprintf("user %s is not a member of project %s", user, projectId))
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is not a member of project %s", user, projectId))}
+	}
+
+	return nil
+}
+
+// VerifyWriteAccessProject checks if "user" is a member of the project "id", who is allowed to change things. This
+// excludes viewers.
+func (s *Store) VerifyWriteAccessProject(projectId string, user string) error {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE project_id=$1 AND user_id=$2 AND role=ANY($3)", memberTable)
+
+	s.LogQuery(query, projectId, user, writingRoles)
+	rows, err := s.tx.Query(query, projectId, user, pq.Array(writingRoles))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("error verifying write access of user %s in project %s", user, projectId))
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is not a member with write access of project %s", user, projectId))}
 	}
 
 	return nil
@@ -66,7 +104,7 @@ func (s *Store) VerifyMembershipProject(projectId string, user string) error {
 
 // VerifyMembershipTask checks if "user" is a member of the project, where the given task with "id" is in.
 func (s *Store) VerifyMembershipTask(taskId string, user string) error {
-	query := fmt.Sprintf("SELECT * FROM %s p, %s t WHERE t.project_id = p.id AND t.id = $1 AND $2=ANY(p.users);", projectTable, taskTable)
+	query := fmt.Sprintf("SELECT * FROM %s m, %s t WHERE t.project_id = m.project_id AND t.id = $1 AND m.user_id = $2;", memberTable, taskTable)
 
 	s.LogQuery(query, taskId, user)
 	rows, err := s.tx.Query(query, taskId, user)
@@ -77,7 +115,26 @@ func (s *Store) VerifyMembershipTask(taskId string, user string) error {
 
 	// If there's a next row, then the given task in in the list of a project where the given user is a member of.
 	if !rows.Next() {
-		return errors.New(fmt.Sprintf("user %s is not a member of the project where the task %s is in", user, taskId))
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is not a member of the project where the task %s is in", user, taskId))}
+	}
+
+	return nil
+}
+
+// VerifyWriteAccessTask checks if "user" is a member with write access (so no viewer) of the project, where the given
+// task is in.
+func (s *Store) VerifyWriteAccessTask(taskId string, user string) error {
+	query := fmt.Sprintf("SELECT * FROM %s m, %s t WHERE t.project_id = m.project_id AND t.id = $1 AND m.user_id = $2 AND m.role = ANY($3);", memberTable, taskTable)
+
+	s.LogQuery(query, taskId, user, writingRoles)
+	rows, err := s.tx.Query(query, taskId, user, pq.Array(writingRoles))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("error verifying write access of user %s for task %s", user, taskId))
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is not a member with write access of the project where the task %s is in", user, taskId))}
 	}
 
 	return nil
@@ -85,7 +142,7 @@ func (s *Store) VerifyMembershipTask(taskId string, user string) error {
 
 // VerifyMembershipTask checks if "user" is a member of the projects, where the given tasks are in.
 func (s *Store) VerifyMembershipTasks(taskIds []string, user string) error {
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s p, %s t WHERE t.project_id = p.id AND t.id = ANY($1) AND $2=ANY(p.users);", projectTable, taskTable)
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s m, %s t WHERE t.project_id = m.project_id AND t.id = ANY($1) AND m.user_id = $2;", memberTable, taskTable)
 
 	s.LogQuery(query, pq.Array(taskIds), user)
 	rows, err := s.tx.Query(query, pq.Array(taskIds), user)
@@ -96,7 +153,7 @@ func (s *Store) VerifyMembershipTasks(taskIds []string, user string) error {
 
 	// If there's a next row, then the given task in in the list of a project where the given user is a member of.
 	if !rows.Next() {
-		return errors.New(fmt.Sprintf("user %s is not a member of all projects where the tasks %v are in", user, taskIds))
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is not a member of all projects where the tasks %v are in", user, taskIds))}
 	}
 
 	var taskMemberships int
@@ -106,19 +163,20 @@ func (s *Store) VerifyMembershipTasks(taskIds []string, user string) error {
 	}
 
 	if taskMemberships != len(taskIds) {
-		return errors.New(fmt.Sprintf("user %s is not a member of all %d tasks (only of %d)", user, len(taskIds), taskMemberships))
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is not a member of all %d tasks (only of %d)", user, len(taskIds), taskMemberships))}
 	}
 
 	return nil
 }
 
-// VerifyCanUnassign returns an error when the given user is not allowed to unassign the current user of the given task.
+// VerifyCanUnassign returns an error when the given user is not allowed to unassign the current user of the given
+// task. This is allowed for the assigned user, the owner and the moderators of the project.
 func (s *Store) VerifyCanUnassign(taskId string, user string) error {
-	// Get task only if the given user is assigned OR the given user is the owner of the project.
-	query := fmt.Sprintf("SELECT * FROM %s t WHERE t.id=$1 AND (t.assigned_user=$2 OR (SELECT p.owner FROM projects p WHERE p.id = t.project_id)=$2);", taskTable)
+	// Get task only if the given user is assigned OR the given user is owner or moderator of the project.
+	query := fmt.Sprintf("SELECT * FROM %s t WHERE t.id=$1 AND (t.assigned_user=$2 OR EXISTS(SELECT * FROM %s m WHERE m.project_id = t.project_id AND m.user_id=$2 AND m.role=ANY($3)));", taskTable, memberTable)
 
-	s.LogQuery(query, taskId, user)
-	rows, err := s.tx.Query(query, taskId, user)
+	s.LogQuery(query, taskId, user, moderatingRoles)
+	rows, err := s.tx.Query(query, taskId, user, pq.Array(moderatingRoles))
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("error verifying assignment of user %s to task %s", user, taskId))
 	}
@@ -126,40 +184,84 @@ func (s *Store) VerifyCanUnassign(taskId string, user string) error {
 
 	// If there's a next row, then the given user is assigned to the given task
 	if !rows.Next() {
-		return errors.New(fmt.Sprintf("user %s is not assigned to task %s", user, taskId))
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is not assigned to task %s", user, taskId))}
 	}
 
 	return nil
 }
 
-// AssignmentInProjectNeeded determines whether a user needs to be assigned to tasks in this project.
-func (s *Store) AssignmentInProjectNeeded(projectId string) (bool, error) {
-	query := fmt.Sprintf("SELECT ARRAY_LENGTH(users, 1) FROM %s WHERE id=$1;", projectTable)
+// GetRole returns the role of the user within the given project. An InvalidRequestError is returned when the user is
+// not a member.
+func (s *Store) GetRole(projectId string, user string) (Role, error) {
+	query := fmt.Sprintf("SELECT role FROM %s WHERE project_id=$1 AND user_id=$2", memberTable)
 
-	s.LogQuery(query, projectId)
-	rows, err := s.tx.Query(query, projectId)
+	s.LogQuery(query, projectId, user)
+	rows, err := s.tx.Query(query, projectId, user)
 	if err != nil {
-		return true, errors.Wrap(err, fmt.Sprintf("error getting assignment requirement for project %s", projectId))
+		return "", errors.Wrap(err, fmt.Sprintf("error getting role of user %s in project %s", user, projectId))
 	}
 	defer rows.Close()
 
 	if !rows.Next() {
-		return true, errors.New(fmt.Sprintf("no row to get assignment requirement for project %s", projectId))
+		return "", &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("user %s is not a member of project %s", user, projectId))}
 	}
 
-	var userCount int
-	err = rows.Scan(&userCount)
+	var role Role
+	err = rows.Scan(&role)
+	if err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("error reading role of user %s in project %s", user, projectId))
+	}
+
+	return role, nil
+}
+
+// VerifyCanValidate returns an error when the given user is not allowed to validate the given task. All members with
+// write access to the project except the mapper of the task are allowed to validate it.
+func (s *Store) VerifyCanValidate(taskId string, user string) error {
+	query := fmt.Sprintf("SELECT * FROM %s m, %s t WHERE t.project_id = m.project_id AND t.id = $1 AND m.user_id = $2 AND m.role = ANY($3) AND t.mapper <> $2;", memberTable, taskTable)
+
+	s.LogQuery(query, taskId, user, writingRoles)
+	rows, err := s.tx.Query(query, taskId, user, pq.Array(writingRoles))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("error verifying that user %s can validate task %s", user, taskId))
+	}
+	defer rows.Close()
+
+	// If there's a next row, then the user is a member and not the mapper of the task
+	if !rows.Next() {
+		return &PermissionError{errors.New(fmt.Sprintf("user %s is not a member of the project of task %s or is the mapper of the task", user, taskId))}
+	}
+
+	return nil
+}
+
+// ValidationInTaskNeeded determines whether finished tasks of the project, where the given task is in, need to be validated.
+func (s *Store) ValidationInTaskNeeded(taskId string) (bool, error) {
+	query := fmt.Sprintf("SELECT p.needs_validation FROM %s p, %s t WHERE $1 = t.id AND t.project_id = p.id;", projectTable, taskTable)
+
+	s.LogQuery(query, taskId)
+	rows, err := s.tx.Query(query, taskId)
 	if err != nil {
-		return true, errors.Wrap(err, fmt.Sprintf("error reading row to get assignment requirement for project %s", projectId))
+		return false, errors.Wrap(err, fmt.Sprintf("error getting validation requirement for task %s", taskId))
 	}
+	defer rows.Close()
 
-	// Tasks in a project with only one user (the owner) don't need an assignment
-	return userCount != 1, nil
+	if !rows.Next() {
+		return false, errors.New(fmt.Sprintf("no row to get validation requirement for task %s", taskId))
+	}
+
+	var needsValidation bool
+	err = rows.Scan(&needsValidation)
+	if err != nil {
+		return false, errors.Wrap(err, fmt.Sprintf("error reading row to get validation requirement for task %s", taskId))
+	}
+
+	return needsValidation, nil
 }
 
 // AssignmentInTaskNeeded determines whether a user needs to be assigned to this task.
 func (s *Store) AssignmentInTaskNeeded(taskId string) (bool, error) {
-	query := fmt.Sprintf("SELECT ARRAY_LENGTH(p.users, 1) FROM %s p, %s t WHERE $1 = t.id AND t.project_id = p.id;", projectTable, taskTable)
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s m, %s t WHERE $1 = t.id AND t.project_id = m.project_id GROUP BY t.id;", memberTable, taskTable)
 
 	s.LogQuery(query, taskId)
 	rows, err := s.tx.Query(query, taskId)
@@ -178,6 +280,11 @@ func (s *Store) AssignmentInTaskNeeded(taskId string) (bool, error) {
 		return true, errors.Wrap(err, fmt.Sprintf("error reading row to get assignment requirement for task %s", taskId))
 	}
 
-	// Tasks in a project with only one user (the owner) don't need an assignment
-	return userCount != 1, nil
+	return AssignmentNeeded(userCount), nil
+}
+
+// AssignmentNeeded determines whether a user needs to be assigned to tasks of a project with the given number of
+// members. Tasks in a project with only one user (the owner) don't need an assignment.
+func AssignmentNeeded(memberCount int) bool {
+	return memberCount != 1
 }
@@ -184,32 +184,6 @@ func TestVerifyAssignment(t *testing.T) {
 	})
 }
 
-func TestAssignmentInProjectNeeded(t *testing.T) {
-	h.Run(t, func() error {
-		assignmentNeeded, err := s.AssignmentInProjectNeeded("3")
-		if err != nil {
-			return fmt.Errorf("Error getting assignment requirement")
-		}
-
-		if assignmentNeeded {
-			return fmt.Errorf("Should not need assignments")
-		}
-
-		// project with multiple users
-
-		assignmentNeeded, err = s.AssignmentInProjectNeeded("2")
-		if err != nil {
-			return fmt.Errorf("Error getting assignment requirement")
-		}
-
-		if !assignmentNeeded {
-			return fmt.Errorf("Should need assignments")
-		}
-
-		return nil
-	})
-}
-
 func TestAssignmentInTaskNeeded(t *testing.T) {
 	h.Run(t, func() error {
 		// Assignment not needed
@@ -242,3 +216,103 @@ func TestAssignmentInTaskNeeded(t *testing.T) {
 		return nil
 	})
 }
+
+func TestVerifyModeration(t *testing.T) {
+	h.Run(t, func() error {
+		_, err := tx.Exec("UPDATE project_members SET role='MODERATOR' WHERE project_id=2 AND user_id='John'")
+		if err != nil {
+			return err
+		}
+
+		err = s.VerifyModeration("2", "Maria")
+		if err != nil {
+			return fmt.Errorf("The owner should be able to moderate: %s", err.Error())
+		}
+
+		err = s.VerifyModeration("2", "John")
+		if err != nil {
+			return fmt.Errorf("A moderator should be able to moderate: %s", err.Error())
+		}
+
+		err = s.VerifyModeration("2", "Anna")
+		if err == nil {
+			return fmt.Errorf("Anna is just a member and should not be able to moderate")
+		}
+
+		err = s.VerifyModeration("1", "John")
+		if err == nil {
+			return fmt.Errorf("John is not a member of project '1'")
+		}
+
+		return nil
+	})
+}
+
+func TestVerifyWriteAccess(t *testing.T) {
+	h.Run(t, func() error {
+		_, err := tx.Exec("UPDATE project_members SET role='VIEWER' WHERE project_id=2 AND user_id='Anna'")
+		if err != nil {
+			return err
+		}
+
+		err = s.VerifyWriteAccessProject("2", "John")
+		if err != nil {
+			return fmt.Errorf("A member should have write access: %s", err.Error())
+		}
+
+		err = s.VerifyWriteAccessTask("3", "Maria")
+		if err != nil {
+			return fmt.Errorf("The owner should have write access: %s", err.Error())
+		}
+
+		err = s.VerifyWriteAccessProject("2", "Anna")
+		if err == nil {
+			return fmt.Errorf("A viewer should not have write access to the project")
+		}
+
+		err = s.VerifyWriteAccessTask("3", "Anna")
+		if err == nil {
+			return fmt.Errorf("A viewer should not have write access to the task")
+		}
+
+		// Viewers are still members
+		err = s.VerifyMembershipTask("3", "Anna")
+		if err != nil {
+			return fmt.Errorf("A viewer should be a member of the project: %s", err.Error())
+		}
+
+		err = s.VerifyWriteAccessTask("3", "Peter")
+		if err == nil {
+			return fmt.Errorf("Peter is not a member of the project of task '3'")
+		}
+
+		return nil
+	})
+}
+
+func TestGetRole(t *testing.T) {
+	h.Run(t, func() error {
+		role, err := s.GetRole("2", "Maria")
+		if err != nil {
+			return fmt.Errorf("Getting role should work: %s", err.Error())
+		}
+		if role != Owner {
+			return fmt.Errorf("Maria should be owner but has role %s", role)
+		}
+
+		role, err = s.GetRole("2", "John")
+		if err != nil {
+			return fmt.Errorf("Getting role should work: %s", err.Error())
+		}
+		if role != Member {
+			return fmt.Errorf("John should be member but has role %s", role)
+		}
+
+		_, err = s.GetRole("1", "John")
+		if err == nil {
+			return fmt.Errorf("John is not a member of project '1' and should have no role")
+		}
+
+		return nil
+	})
+}
@@ -1,6 +1,9 @@
 package project
 
-import "stm/task"
+import (
+	"stm/task"
+	"time"
+)
 
 type AddDto struct {
 	Project DraftDto        `json:"project"`
@@ -8,15 +11,27 @@ type AddDto struct {
 }
 
 type DraftDto struct {
-	Name           string         `json:"name"`           // Name of the project. Must not be NULL or empty.
-	Description    string         `json:"description"`    // Description of the project. Must not be NULL but cam be empty.
-	Users          []string       `json:"users"`          // A non-empty list of user-IDs. At least the owner should be in here.
-	Owner          string         `json:"owner"`          // The user-ID who created this project. Must not be NULL or empty.
-	JosmDataSource JosmDataSource `json:"josmDataSource"` // The source JOSM should load the data from when opening a task in JOSM.
+	Name              string         `json:"name"`              // Name of the project. Must not be NULL or empty.
+	Description       string         `json:"description"`       // Description of the project. Must not be NULL but cam be empty.
+	Users             []string       `json:"users"`             // A non-empty list of user-IDs. At least the owner should be in here.
+	Owner             string         `json:"owner"`             // The user-ID who created this project. Must not be NULL or empty.
+	JosmDataSource    JosmDataSource `json:"josmDataSource"`    // The source JOSM should load the data from when opening a task in JOSM.
+	AssignmentTimeout int            `json:"assignmentTimeout"` // Number of hours after which assignments expire. 0 means assignments never expire.
+	NeedsValidation   bool           `json:"needsValidation"`   // When "true", finished tasks have to be validated by a different member of the project.
+	Public            bool           `json:"public"`            // When "true", every user can join this project.
+	Boundary          string         `json:"boundary"`          // Optional area of the project as GeoJSON polygon or multi-polygon (geometry or feature).
 }
 
 type UpdateDto struct {
-	Name           string         `json:"name"`           // Name of the project. Must not be NULL or empty.
-	Description    string         `json:"description"`    // Description of the project. Must not be NULL but cam be empty.
-	JosmDataSource JosmDataSource `json:"josmDataSource"` // The source JOSM should load the data from when opening a task in JOSM.
+	Name              string         `json:"name"`              // Name of the project. Must not be NULL or empty.
+	Description       string         `json:"description"`       // Description of the project. Must not be NULL but cam be empty.
+	JosmDataSource    JosmDataSource `json:"josmDataSource"`    // The source JOSM should load the data from when opening a task in JOSM.
+	AssignmentTimeout int            `json:"assignmentTimeout"` // Number of hours after which assignments expire. 0 means assignments never expire.
+	NeedsValidation   bool           `json:"needsValidation"`   // When "true", finished tasks have to be validated by a different member of the project.
+	Public            bool           `json:"public"`            // When "true", every user can join this project.
+}
+
+type InvitationDto struct {
+	Token      string    `json:"token"`      // The token to join the project with. It's URL-safe and can be used within links.
+	ValidUntil time.Time `json:"validUntil"` // UTC date in RFC 3339 format after which the token can't be used anymore.
 }
@@ -2,6 +2,7 @@ package project
 
 import (
 	"stm/comment"
+	"stm/permission"
 	"stm/task"
 	"time"
 )
@@ -21,6 +22,7 @@ type Project struct {
 	Users []string `json:"users"` // Array of user-IDs (=members of this project). Will not be NULL or empty.
 	// TODO Use "Id" as suffix?
 	Owner              string            `json:"owner"`              // User-ID of the owner/creator of this project. Will not be NULL or empty.
+	Members            []*Member         `json:"members"`            // The members of this project with their roles in the same order as the "Users" list. Will not be NULL or empty.
 	Description        string            `json:"description"`        // Some description, can be empty. Will not be NULL but might be empty.
 	NeedsAssignment    bool              `json:"needsAssignment"`    // When "true", the tasks of this project need to have an assigned user.
 	TotalProcessPoints int               `json:"totalProcessPoints"` // Sum of all maximum process points of all tasks.
@@ -28,4 +30,72 @@ type Project struct {
 	CreationDate       *time.Time        `json:"creationDate"`       // UTC Date in RFC 3339 format, can be NIL because of old data in the database. Example: "2006-01-02 15:04:05.999999999 -0700 MST"
 	Comments           []comment.Comment `json:"comments"`           // The comment on the project.
 	JosmDataSource     JosmDataSource    `json:"josmDataSource"`     // The source JOSM should load the data from when opening a task in JOSM.
+	AssignmentTimeout  int               `json:"assignmentTimeout"`  // Number of hours after which the assigned user of a task is removed automatically. 0 means assignments never expire.
+	NeedsValidation    bool              `json:"needsValidation"`    // When "true", finished tasks have to be validated by a different member of the project.
+	Public             bool              `json:"public"`             // When "true", every user can join this project.
+	Boundary           string            `json:"boundary"`           // The area of the project as GeoJSON polygon or multi-polygon geometry. Will be empty when the project has no boundary.
+}
+
+// Summary contains the key figures of a project without loading its tasks, members and comments.
+type Summary struct {
+	Id                 string     `json:"id"`                 // The ID of the project.
+	Name               string     `json:"name"`               // The name of the project.
+	Owner              string     `json:"owner"`              // User-ID of the owner of this project.
+	NumberOfUsers      int        `json:"numberOfUsers"`      // Number of members of the project.
+	NumberOfTasks      int        `json:"numberOfTasks"`      // Number of tasks of the project.
+	DoneTasks          int        `json:"doneTasks"`          // Number of tasks where all process points have been set.
+	TotalProcessPoints int        `json:"totalProcessPoints"` // Sum of all maximum process points of all tasks.
+	DoneProcessPoints  int        `json:"doneProcessPoints"`  // Sum of all process points that have been set.
+	CreationDate       *time.Time `json:"creationDate"`       // UTC Date in RFC 3339 format, can be NIL because of old data in the database.
+	LastActivity       *time.Time `json:"lastActivity"`       // UTC Date of the latest task change or comment. NIL when nothing happened in the project yet.
+}
+
+type SummaryPage struct {
+	Summaries []*Summary `json:"summaries"` // The summaries of the requested page.
+	Total     int        `json:"total"`     // Number of all projects of the user.
+	Offset    int        `json:"offset"`    // Number of skipped projects.
+	Limit     int        `json:"limit"`     // Maximum number of summaries on this page.
+}
+
+// SummarySort is the attribute by which project summaries are sorted.
+type SummarySort string
+
+const (
+	SortById           SummarySort = "id"
+	SortByName         SummarySort = "name"
+	SortByCreationDate SummarySort = "creationDate"
+	SortByLastActivity SummarySort = "lastActivity"
+	SortByProgress     SummarySort = "progress"
+)
+
+const (
+	DefaultSummaryLimit = 50  // Number of project summaries on a page, when no limit is requested.
+	MaxSummaryLimit     = 500 // Maximum number of project summaries on one page.
+)
+
+type Member struct {
+	UserId string          `json:"userId"` // The user-ID of the member.
+	Role   permission.Role `json:"role"`   // One of "OWNER", "MODERATOR", "MEMBER" and "VIEWER".
+}
+
+type Statistics struct {
+	ProjectId          string                `json:"projectId"`          // The ID of the project.
+	NumberOfTasks      int                   `json:"numberOfTasks"`      // Number of all tasks of the project.
+	UntouchedTasks     int                   `json:"untouchedTasks"`     // Number of tasks without any process points.
+	InProgressTasks    int                   `json:"inProgressTasks"`    // Number of tasks with some but not all process points.
+	DoneTasks          int                   `json:"doneTasks"`          // Number of tasks where all process points have been set.
+	NeedsReviewTasks   int                   `json:"needsReviewTasks"`   // Number of tasks waiting for validation.
+	ValidatedTasks     int                   `json:"validatedTasks"`     // Number of validated tasks.
+	AssignedTasks      int                   `json:"assignedTasks"`      // Number of tasks which currently have an assigned user.
+	TotalProcessPoints int                   `json:"totalProcessPoints"` // Sum of all maximum process points of all tasks.
+	DoneProcessPoints  int                   `json:"doneProcessPoints"`  // Sum of all process points that have been set.
+	Users              []*UserStatistics     `json:"users"`              // Contribution of each member and each user who made changes to tasks of this project. Ordered by user-ID.
+	Progress           []*task.ProgressEntry `json:"progress"`           // Process point changes per interval. Intervals without changes are omitted.
+}
+
+type UserStatistics struct {
+	UserId        string `json:"userId"`        // The user-ID.
+	ProcessPoints int    `json:"processPoints"` // Sum of all process point changes made by this user.
+	TouchedTasks  int    `json:"touchedTasks"`  // Number of tasks this user has been assigned to or has set process points on.
+	AssignedTasks int    `json:"assignedTasks"` // Number of tasks this user is currently assigned to.
 }
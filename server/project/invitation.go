@@ -0,0 +1,110 @@
+package project
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"stm/config"
+	"stm/util"
+	"time"
+)
+
+// invitation is the content of an invitation token. The token is the base64 encoded JSON of this struct.
+type invitation struct {
+	ProjectId  string `json:"project_id"`
+	ValidUntil int64  `json:"valid_until"`
+	Secret     string `json:"secret"`
+}
+
+var (
+	invitationKey      []byte
+	invitationValidity time.Duration
+)
+
+// InitInvitations derives the key to sign invitations with from the configured invitation secret or, if there's none,
+// from the OAuth2 secret. Invitations therefore stay valid when the server restarts.
+func InitInvitations() error {
+	secret := config.Conf.InvitationSecret
+	if secret == "" {
+		secret = config.Conf.Oauth2Secret
+	}
+	if secret == "" {
+		return errors.New("no secret to sign invitations with")
+	}
+
+	// Derive a separate key to not use the OAuth2 secret directly
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write([]byte("stm-invitations"))
+	invitationKey = hash.Sum(nil)
+
+	var err error
+	invitationValidity, err = time.ParseDuration(config.Conf.InvitationValidity)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse invitation validity '%s'", config.Conf.InvitationValidity)
+	}
+
+	return nil
+}
+
+// createInvitationToken creates a token for the project. The nonce is the current invitation nonce of the project,
+// which is changed to revoke all existing invitations.
+func createInvitationToken(projectId string, validUntil int64, nonce string) (string, error) {
+	token := &invitation{
+		ProjectId:  projectId,
+		ValidUntil: validUntil,
+		Secret:     createInvitationSecret(projectId, validUntil, nonce),
+	}
+
+	jsonBytes, err := json.Marshal(token)
+	if err != nil {
+		return "", errors.Wrap(err, "error marshalling invitation object")
+	}
+
+	// The token is used within links, so the URL-safe encoding is used here
+	return base64.URLEncoding.EncodeToString(jsonBytes), nil
+}
+
+// createInvitationSecret builds a new secret string encoded as base64. This uses HMAC with SHA-256 inside.
+func createInvitationSecret(projectId string, validUntil int64, nonce string) string {
+	secretBaseString := fmt.Sprintf("%s\n%d\n%s\n", projectId, validUntil, nonce)
+
+	hash := hmac.New(sha256.New, invitationKey)
+	hash.Write([]byte(secretBaseString))
+
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// decodeInvitationToken turns the token into an invitation without verifying it. Use verify to make sure the
+// invitation is valid.
+func decodeInvitationToken(encodedToken string) (*invitation, error) {
+	tokenBytes, err := base64.URLEncoding.DecodeString(encodedToken)
+	if err != nil {
+		return nil, &util.InvalidRequestError{Err: errors.Wrap(err, "error decoding invitation")}
+	}
+
+	var token invitation
+	err = json.Unmarshal(tokenBytes, &token)
+	if err != nil {
+		return nil, &util.InvalidRequestError{Err: errors.Wrap(err, "error unmarshalling invitation object")}
+	}
+
+	return &token, nil
+}
+
+// verify checks the secret and the expiration of the invitation. The nonce is the current invitation nonce of the
+// project.
+func (i *invitation) verify(nonce string) error {
+	targetSecret := createInvitationSecret(i.ProjectId, i.ValidUntil, nonce)
+	if !hmac.Equal([]byte(i.Secret), []byte(targetSecret)) {
+		return &util.InvalidRequestError{Err: errors.New("invitation not valid")}
+	}
+
+	if i.ValidUntil < time.Now().Unix() {
+		return &util.InvalidRequestError{Err: errors.New("invitation expired")}
+	}
+
+	return nil
+}
@@ -3,8 +3,10 @@ package project
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"stm/comment"
 	"stm/config"
+	"stm/geometry"
 	"stm/permission"
 	"stm/task"
 	"stm/util"
@@ -40,15 +42,68 @@ func (s *Service) GetProjects(userId string) ([]*Project, error) {
 		return nil, err
 	}
 
+	err = s.addTasksAndMetadataToProjects(projects)
+	if err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// GetProjectsInBoundingBox returns the projects of the user whose boundary or tasks intersect the given bounding box.
+func (s *Service) GetProjectsInBoundingBox(userId string, bbox *geometry.BoundingBox) ([]*Project, error) {
+	projects, err := s.store.getProjectsOfUserInBoundingBox(userId, bbox)
+	if err != nil {
+		s.Err("Error getting projects for user %s within bounding box %v", userId, *bbox)
+		return nil, err
+	}
+
+	err = s.addTasksAndMetadataToProjects(projects)
+	if err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// GetSummaries returns one page of summaries of all projects of the user. The summaries don't contain tasks, members
+// and comments, which makes them much cheaper than the projects themselves.
+func (s *Service) GetSummaries(userId string, sort SummarySort, descending bool, offset int, limit int) (*SummaryPage, error) {
+	if offset < 0 {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("Offset must not be negative (%d)", offset))}
+	}
+	if limit <= 0 || limit > MaxSummaryLimit {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("Limit must be between 1 and %d but was %d", MaxSummaryLimit, limit))}
+	}
+
+	summaries, err := s.store.getSummariesOfUser(userId, sort, descending, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.store.getNumberOfProjectsOfUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SummaryPage{
+		Summaries: summaries,
+		Total:     total,
+		Offset:    offset,
+		Limit:     limit,
+	}, nil
+}
+
+func (s *Service) addTasksAndMetadataToProjects(projects []*Project) error {
 	for _, p := range projects {
-		err = s.addTasksAndMetadata(p)
+		err := s.addTasksAndMetadata(p)
 		if err != nil {
 			s.Err("Unable to add process point data to project %s", p.Id)
-			return nil, err
+			return err
 		}
 	}
 
-	return projects, nil
+	return nil
 }
 
 func (s *Service) GetProjectByTask(taskId string) (*Project, error) {
@@ -68,7 +123,7 @@ func (s *Service) GetProjectByTask(taskId string) (*Project, error) {
 }
 
 // AddProjectWithTasks takes the project and the tasks and adds them to the database. This also adds the process-point
-// metadata to the returned project.
+// metadata to the returned project. The tasks of the returned project are in the same order as the given drafts.
 func (s *Service) AddProjectWithTasks(projectDraft *DraftDto, taskDrafts []task.DraftDto) (*Project, error) {
 	if len(taskDrafts) > config.Conf.MaxTasksPerProject {
 		return nil, errors.New(fmt.Sprintf("Maximum %d tasks allowed", config.Conf.MaxTasksPerProject))
@@ -122,6 +177,16 @@ func (s *Service) AddProject(projectDraft *DraftDto) (*Project, error) {
 		return nil, errors.New(fmt.Sprintf("Description too long. Allowed are %d characters but found %d.", config.Conf.MaxDescriptionLength, utf8.RuneCountInString(projectDraft.Description)))
 	}
 
+	if projectDraft.AssignmentTimeout < 0 {
+		return nil, errors.New(fmt.Sprintf("Assignment timeout must not be negative (%d)", projectDraft.AssignmentTimeout))
+	}
+
+	boundary, err := toBoundary(projectDraft.Boundary)
+	if err != nil {
+		return nil, err
+	}
+	projectDraft.Boundary = boundary
+
 	// Actually add project
 	project, err := s.store.addProject(projectDraft, time.Now().UTC())
 	if err != nil {
@@ -160,24 +225,87 @@ func (s *Service) addTasksAndMetadata(project *Project) error {
 		project.TotalProcessPoints += t.MaxProcessPoints
 	}
 
-	needsAssignment, err := s.permissionStore.AssignmentInProjectNeeded(project.Id)
-	if err != nil {
-		s.Err("unable to get assignment requirement for project %s", project.Id)
-		return err
-	}
-	project.NeedsAssignment = needsAssignment
+	// The members are already loaded, so there's no need to ask the database again
+	project.NeedsAssignment = permission.AssignmentNeeded(len(project.Members))
 
 	s.Log("Added task metadata to project %s", project.Id)
 
 	return nil
 }
 
-func (s *Service) AddUser(projectId, userId, potentialOwnerId string) (*Project, error) {
-	err := s.permissionStore.VerifyOwnership(projectId, potentialOwnerId)
+// AddUser adds the user as normal member to the project. The requesting user must be the owner or a moderator.
+func (s *Service) AddUser(projectId, userId, requestingUserId string) (*Project, error) {
+	err := s.permissionStore.VerifyModeration(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.addMember(projectId, userId)
+}
+
+// JoinProject adds the requesting user as member to the project. This is only possible for public projects.
+func (s *Service) JoinProject(projectId, requestingUserId string) (*Project, error) {
+	p, err := s.store.getProject(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.Public {
+		return nil, &permission.PermissionError{Err: errors.New(fmt.Sprintf("project %s is not public, user %s needs an invitation to join", projectId, requestingUserId))}
+	}
+
+	return s.addMember(projectId, requestingUserId)
+}
+
+// CreateInvitation creates a signed and expiring token, which can be used by any user to join the project. The
+// requesting user must be the owner or a moderator of the project.
+func (s *Service) CreateInvitation(projectId, requestingUserId string) (*InvitationDto, error) {
+	err := s.permissionStore.VerifyModeration(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := s.store.getInvitationNonce(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	validUntil := time.Now().UTC().Add(invitationValidity).Truncate(time.Second)
+
+	token, err := createInvitationToken(projectId, validUntil.Unix(), nonce)
 	if err != nil {
 		return nil, err
 	}
+	s.Log("Created invitation for project %s valid until %s", projectId, validUntil)
+
+	return &InvitationDto{
+		Token:      token,
+		ValidUntil: validUntil,
+	}, nil
+}
+
+// RedeemInvitation adds the requesting user as member to the project of the given invitation token.
+func (s *Service) RedeemInvitation(token, requestingUserId string) (*Project, error) {
+	invitation, err := decodeInvitationToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := s.store.getInvitationNonce(invitation.ProjectId)
+	if err != nil {
+		return nil, &util.InvalidRequestError{Err: errors.Wrap(err, "invitation not valid")}
+	}
+
+	err = invitation.verify(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.addMember(invitation.ProjectId, requestingUserId)
+}
 
+// addMember adds the user as member to the project without any permission checks.
+func (s *Service) addMember(projectId, userId string) (*Project, error) {
 	p, err := s.store.getProject(projectId)
 	if err != nil {
 		return nil, err
@@ -186,11 +314,11 @@ func (s *Service) AddUser(projectId, userId, potentialOwnerId string) (*Project,
 	// Check if userId is already in project. If so, just do nothing and return
 	for _, u := range p.Users {
 		if u == userId {
-			return p, errors.New("User already added")
+			return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("user %s is already a member of project %s", userId, projectId))}
 		}
 	}
 
-	project, err := s.store.addUser(projectId, userId)
+	project, err := s.store.addUser(projectId, userId, permission.Member)
 	if err != nil {
 		return nil, err
 	}
@@ -224,12 +352,24 @@ func (s *Service) RemoveUser(projectId, requestingUserId, userIdToRemove string)
 		return nil, errors.New("removing the owner is not allowed")
 	}
 
-	err = s.permissionStore.VerifyOwnership(projectId, requestingUserId)
-	requestingUserIsOwner := err == nil
+	// When a user tries to remove a different user, only the owner and moderators are allowed to do that
+	if requestingUserId != userIdToRemove {
+		err = s.permissionStore.VerifyModeration(projectId, requestingUserId)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("user '%s' is neither owner nor moderator and not allowed to remove another user", requestingUserId))
+		}
 
-	// When a user tries to remove a different user, only the owner is allowed to do that
-	if requestingUserId != userIdToRemove && !requestingUserIsOwner {
-		return nil, errors.New(fmt.Sprintf("non-owner user '%s' is not allowed to remove another user", requestingUserId))
+		// Moderators can only be removed by the owner
+		roleToRemove, err := s.permissionStore.GetRole(projectId, userIdToRemove)
+		if err != nil {
+			return nil, err
+		}
+		if roleToRemove == permission.Moderator {
+			err = s.permissionStore.VerifyOwnership(projectId, requestingUserId)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("only the owner is allowed to remove the moderator '%s'", userIdToRemove))
+			}
+		}
 	}
 
 	project, err := s.store.removeUser(projectId, userIdToRemove)
@@ -238,6 +378,21 @@ func (s *Service) RemoveUser(projectId, requestingUserId, userIdToRemove string)
 	}
 	s.Log("User removed from project %s", project.Id)
 
+	// A removed user must not be able to join again using an old invitation. Users leaving the project on their own
+	// don't revoke the invitations of the other users.
+	if requestingUserId != userIdToRemove {
+		nonce, err := util.GetRandomString()
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.store.setInvitationNonce(projectId, nonce)
+		if err != nil {
+			return nil, err
+		}
+		s.Log("Revoked all invitations to project %s", projectId)
+	}
+
 	// Unassign removed user from all tasks
 	newTasks := make([]*task.Task, len(project.Tasks))
 	for i, t := range project.Tasks {
@@ -271,6 +426,252 @@ func (s *Service) RemoveUser(projectId, requestingUserId, userIdToRemove string)
 	return project, nil
 }
 
+// GetStatistics collects the state of all tasks and the contributions of all users to the project. The progress over
+// time is grouped by the given interval. The requesting user must be a member of the project.
+func (s *Service) GetStatistics(projectId string, interval task.ProgressInterval, requestingUserId string) (*Statistics, error) {
+	if interval != task.IntervalDay && interval != task.IntervalWeek && interval != task.IntervalMonth {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("unknown interval '%s'", interval))}
+	}
+
+	project, err := s.GetProject(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	statistics := &Statistics{
+		ProjectId:          project.Id,
+		NumberOfTasks:      len(project.Tasks),
+		TotalProcessPoints: project.TotalProcessPoints,
+		DoneProcessPoints:  project.DoneProcessPoints,
+	}
+
+	users := make(map[string]*UserStatistics)
+	getUser := func(userId string) *UserStatistics {
+		if _, ok := users[userId]; !ok {
+			users[userId] = &UserStatistics{UserId: userId}
+		}
+		return users[userId]
+	}
+
+	for _, u := range project.Users {
+		getUser(u)
+	}
+
+	for _, t := range project.Tasks {
+		switch {
+		case t.ProcessPoints == 0:
+			statistics.UntouchedTasks++
+		case t.ProcessPoints < t.MaxProcessPoints:
+			statistics.InProgressTasks++
+		default:
+			statistics.DoneTasks++
+		}
+
+		switch t.ValidationState {
+		case task.NeedsReview:
+			statistics.NeedsReviewTasks++
+		case task.Validated:
+			statistics.ValidatedTasks++
+		}
+
+		if t.AssignedUser != "" {
+			statistics.AssignedTasks++
+			getUser(t.AssignedUser).AssignedTasks++
+		}
+	}
+
+	contributions, err := s.store.taskStore.GetContributions(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range contributions {
+		user := getUser(c.UserId)
+		user.ProcessPoints = c.ProcessPoints
+		user.TouchedTasks = c.TouchedTasks
+	}
+
+	statistics.Users = make([]*UserStatistics, 0, len(users))
+	for _, u := range users {
+		statistics.Users = append(statistics.Users, u)
+	}
+	sort.Slice(statistics.Users, func(i, j int) bool {
+		return statistics.Users[i].UserId < statistics.Users[j].UserId
+	})
+
+	statistics.Progress, err = s.store.taskStore.GetProgress(projectId, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	return statistics, nil
+}
+
+// SetRole changes the role of a member of the project. Only the owner is allowed to do that. The role of the owner
+// can't be changed and nobody can become owner this way.
+func (s *Service) SetRole(projectId string, userId string, role permission.Role, requestingUserId string) (*Project, error) {
+	err := s.permissionStore.VerifyOwnership(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !permission.IsValidRole(role) {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("unknown role '%s'", role))}
+	}
+	if role == permission.Owner {
+		return nil, &util.InvalidRequestError{Err: errors.New("the owner role can't be given to another member")}
+	}
+
+	currentRole, err := s.permissionStore.GetRole(projectId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if currentRole == permission.Owner {
+		return nil, &util.InvalidRequestError{Err: errors.New("the role of the owner can't be changed")}
+	}
+
+	project, err := s.store.setRole(projectId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	s.Log("Set role of user %s in project %s to %s", userId, projectId, role)
+
+	err = s.addTasksAndMetadata(project)
+	if err != nil {
+		s.Err("Unable to add process point data to project %s", project.Id)
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// TransferOwnership makes the given member the new owner of the project. Only the current owner is allowed to do that
+// and stays in the project as moderator.
+func (s *Service) TransferOwnership(projectId string, newOwnerId string, requestingUserId string) (*Project, error) {
+	err := s.permissionStore.VerifyOwnership(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if newOwnerId == requestingUserId {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("user %s is already the owner of project %s", newOwnerId, projectId))}
+	}
+
+	// The new owner not being a member is a problem of the request and not of the permissions of the requesting user
+	_, err = s.permissionStore.GetRole(projectId, newOwnerId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "new owner %s must be a member of project %s", newOwnerId, projectId)
+	}
+
+	project, err := s.store.transferOwnership(projectId, requestingUserId, newOwnerId)
+	if err != nil {
+		return nil, err
+	}
+	s.Log("Transferred ownership of project %s from %s to %s", projectId, requestingUserId, newOwnerId)
+
+	err = s.addTasksAndMetadata(project)
+	if err != nil {
+		s.Err("Unable to add process point data to project %s", project.Id)
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// AddTasks adds new tasks to an existing project, e.g. when the area of the project grows. Only the owner is allowed to
+// do that and the project must not exceed the maximum amount of tasks afterwards.
+func (s *Service) AddTasks(projectId string, taskDrafts []task.DraftDto, requestingUserId string) (*Project, error) {
+	err := s.permissionStore.VerifyOwnership(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(taskDrafts) == 0 {
+		return nil, &util.InvalidRequestError{Err: errors.New("No tasks to add")}
+	}
+
+	project, err := s.store.getProject(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(project.Tasks)+len(taskDrafts) > config.Conf.MaxTasksPerProject {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("Adding %d tasks would result in %d tasks, maximum %d tasks allowed", len(taskDrafts), len(project.Tasks)+len(taskDrafts), config.Conf.MaxTasksPerProject))}
+	}
+
+	_, err = s.taskService.AddTasks(taskDrafts, projectId)
+	if err != nil {
+		return nil, err
+	}
+	s.Log("Added %d tasks to existing project %s", len(taskDrafts), projectId)
+
+	// The project contains the existing as well as the new tasks
+	project.Tasks, err = s.taskService.GetTasks(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.addTasksAndMetadata(project)
+	if err != nil {
+		s.Err("Unable to add process point data to project %s", project.Id)
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// SetBoundary sets the boundary of the project, which is the whole area to map. The boundary is a GeoJSON feature or
+// geometry (polygon or multi-polygon) and is repaired if necessary. An empty boundary removes the current one.
+func (s *Service) SetBoundary(projectId string, boundary string, requestingUserId string) (*Project, error) {
+	err := s.permissionStore.VerifyModeration(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	boundary, err = toBoundary(boundary)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := s.store.setBoundary(projectId, boundary)
+	if err != nil {
+		return nil, err
+	}
+	s.Log("Set boundary of project %s", project.Id)
+
+	err = s.addTasksAndMetadata(project)
+	if err != nil {
+		s.Err("Unable to add process point data to project %s", project.Id)
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// toBoundary validates and repairs the given boundary and turns it into a GeoJSON geometry as stored in the database.
+func toBoundary(boundary string) (string, error) {
+	if boundary == "" {
+		return "", nil
+	}
+
+	boundaryGeometry, err := geometry.ParseAreaGeometry(boundary)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid boundary")
+	}
+
+	boundaryGeometry, _, err = geometry.ValidateGeometry(boundaryGeometry, 0, 0)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid boundary")
+	}
+
+	boundaryJson, err := boundaryGeometry.MarshalJSON()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to serialize boundary")
+	}
+
+	return string(boundaryJson), nil
+}
+
 func (s *Service) DeleteProject(projectId, potentialOwnerId string) error {
 	err := s.permissionStore.VerifyOwnership(projectId, potentialOwnerId)
 	if err != nil {
@@ -287,8 +688,8 @@ func (s *Service) DeleteProject(projectId, potentialOwnerId string) error {
 	return nil
 }
 
-func (s *Service) Update(projectId string, newName string, newDescription string, newJosmDataSource JosmDataSource, requestingUserId string) (*Project, error) {
-	err := s.permissionStore.VerifyOwnership(projectId, requestingUserId)
+func (s *Service) Update(projectId string, newName string, newDescription string, newJosmDataSource JosmDataSource, newAssignmentTimeout int, newNeedsValidation bool, newPublic bool, requestingUserId string) (*Project, error) {
+	err := s.permissionStore.VerifyModeration(projectId, requestingUserId)
 	if err != nil {
 		return nil, err
 	}
@@ -306,7 +707,12 @@ func (s *Service) Update(projectId string, newName string, newDescription string
 		return nil, errors.New(fmt.Sprintf("Description too long. Allowed are %d characters but found %d.", config.Conf.MaxDescriptionLength, utf8.RuneCountInString(newDescription)))
 	}
 
-	project, err := s.store.update(projectId, newName, newDescription, newJosmDataSource)
+	// Check assignment timeout
+	if newAssignmentTimeout < 0 {
+		return nil, errors.New(fmt.Sprintf("Assignment timeout must not be negative (%d)", newAssignmentTimeout))
+	}
+
+	project, err := s.store.update(projectId, newName, newDescription, newJosmDataSource, newAssignmentTimeout, newNeedsValidation, newPublic)
 	if err != nil {
 		return nil, err
 	}
@@ -321,7 +727,13 @@ func (s *Service) Update(projectId string, newName string, newDescription string
 	return project, nil
 }
 
+// AddComment adds the comment to the project. The author must be a member with write access.
 func (s *Service) AddComment(projectId string, draftDto *comment.DraftDto, authorId string) error {
+	err := s.permissionStore.VerifyWriteAccessProject(projectId, authorId)
+	if err != nil {
+		return err
+	}
+
 	commentListId, err := s.store.getCommentListId(projectId)
 	if err != nil {
 		return err
@@ -329,3 +741,14 @@ func (s *Service) AddComment(projectId string, draftDto *comment.DraftDto, autho
 
 	return s.commentService.AddComment(commentListId, draftDto, authorId)
 }
+
+// ImportComments adds the comments with their original authors and dates to the project. There are no permission
+// checks, so this must only be used when importing a project.
+func (s *Service) ImportComments(projectId string, comments []comment.Comment) error {
+	commentListId, err := s.store.getCommentListId(projectId)
+	if err != nil {
+		return err
+	}
+
+	return s.commentService.ImportComments(commentListId, comments)
+}
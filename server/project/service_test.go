@@ -2,9 +2,12 @@ package project
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"stm/comment"
 	"stm/config"
+	"stm/geometry"
 	"stm/permission"
 	"stm/task"
 	"stm/test"
@@ -42,6 +45,9 @@ func setup() {
 	commentService := comment.Init(logger, commentStore)
 	taskService = task.Init(tx, logger, permissionStore, commentService, commentStore)
 	s = Init(tx, logger, taskService, permissionStore, commentService, commentStore)
+
+	err := InitInvitations()
+	sigolo.FatalCheck(err)
 }
 
 func TestGetProjects(t *testing.T) {
@@ -114,6 +120,64 @@ func TestGetProjects(t *testing.T) {
 	})
 }
 
+func TestGetProjectsWithConstantNumberOfQueries(t *testing.T) {
+	h.Run(t, func() error {
+		addProject := func(numberOfTasks int) error {
+			drafts := make([]task.DraftDto, numberOfTasks)
+			for i := range drafts {
+				lon := float64(i) * 0.01
+				drafts[i] = task.DraftDto{
+					MaxProcessPoints: 10,
+					Geometry:         fmt.Sprintf("{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[%f,0],[%f,0],[%f,0.01],[%f,0]]]},\"properties\":null}", lon, lon+0.01, lon+0.01, lon),
+				}
+			}
+
+			_, err := s.AddProjectWithTasks(&DraftDto{Name: "Test project", Users: []string{"Jack", "Jill"}, Owner: "Jack"}, drafts)
+			return err
+		}
+
+		countQueries := func(expectedProjects int) (int, error) {
+			queryCountBefore := h.QueryCount()
+			projects, err := s.GetProjects("Jack")
+			if err != nil {
+				return 0, err
+			}
+			if len(projects) != expectedProjects {
+				return 0, errors.New(fmt.Sprintf("Expected %d projects but got %d", expectedProjects, len(projects)))
+			}
+			return h.QueryCount() - queryCountBefore, nil
+		}
+
+		err := addProject(1)
+		if err != nil {
+			return err
+		}
+		queriesForOneProject, err := countQueries(1)
+		if err != nil {
+			return err
+		}
+
+		err = addProject(5)
+		if err != nil {
+			return err
+		}
+		err = addProject(10)
+		if err != nil {
+			return err
+		}
+		queriesForThreeProjects, err := countQueries(3)
+		if err != nil {
+			return err
+		}
+
+		if queriesForOneProject != queriesForThreeProjects {
+			return errors.New(fmt.Sprintf("Number of queries should not depend on the number of projects and tasks but was %d for one and %d for three projects", queriesForOneProject, queriesForThreeProjects))
+		}
+
+		return nil
+	})
+}
+
 func TestGetProjectsInvalidUser(t *testing.T) {
 	h.Run(t, func() error {
 		// User "Worf" does not exist
@@ -138,6 +202,133 @@ func TestGetProjectsInvalidUser(t *testing.T) {
 	})
 }
 
+func TestGetProjectsInBoundingBox(t *testing.T) {
+	h.Run(t, func() error {
+		// Maria is member of project 1 and 2, both have tasks near 0,0
+		userProjects, err := s.GetProjectsInBoundingBox("Maria", &geometry.BoundingBox{MinLon: -1, MinLat: -1, MaxLon: 1, MaxLat: 1})
+		if err != nil {
+			return err
+		}
+		if len(userProjects) != 2 || userProjects[0].Id != "1" || userProjects[1].Id != "2" {
+			return errors.New(fmt.Sprintf("Projects 1 and 2 should be within bounding box but found %d projects", len(userProjects)))
+		}
+		if len(userProjects[1].Tasks) != 5 {
+			return errors.New("Projects should contain all tasks")
+		}
+
+		// Only project 2 has tasks in Hamburg
+		userProjects, err = s.GetProjectsInBoundingBox("Maria", &geometry.BoundingBox{MinLon: 9.9, MinLat: 53.5, MaxLon: 10.1, MaxLat: 53.6})
+		if err != nil {
+			return err
+		}
+		if len(userProjects) != 1 || userProjects[0].Id != "2" {
+			return errors.New("Only project 2 should be within bounding box")
+		}
+
+		bbox := &geometry.BoundingBox{MinLon: 20, MinLat: 20, MaxLon: 21, MaxLat: 21}
+		userProjects, err = s.GetProjectsInBoundingBox("Maria", bbox)
+		if err != nil {
+			return err
+		}
+		if len(userProjects) != 0 {
+			return errors.New("No project should be within bounding box")
+		}
+
+		// The boundary is considered as well
+		_, err = s.SetBoundary("2", "{\"type\":\"Polygon\",\"coordinates\":[[[19,19],[22,19],[22,22],[19,22],[19,19]]]}", "Maria")
+		if err != nil {
+			return err
+		}
+		userProjects, err = s.GetProjectsInBoundingBox("Maria", bbox)
+		if err != nil {
+			return err
+		}
+		if len(userProjects) != 1 || userProjects[0].Id != "2" {
+			return errors.New("Project 2 should be within bounding box due to its boundary")
+		}
+
+		// Otto is no member of project 2
+		userProjects, err = s.GetProjectsInBoundingBox("Otto", bbox)
+		if err != nil {
+			return err
+		}
+		if len(userProjects) != 0 {
+			return errors.New("Projects of other users should not be returned")
+		}
+
+		return nil
+	})
+}
+
+func TestGetSummaries(t *testing.T) {
+	h.Run(t, func() error {
+		page, err := s.GetSummaries("Maria", SortById, false, 0, DefaultSummaryLimit)
+		if err != nil {
+			return err
+		}
+		if page.Total != 2 || len(page.Summaries) != 2 {
+			return errors.New(fmt.Sprintf("Maria should have 2 projects but page had total %d and %d summaries", page.Total, len(page.Summaries)))
+		}
+
+		summary := page.Summaries[0]
+		if summary.Id != "1" || summary.Owner != "Peter" || summary.NumberOfUsers != 2 || summary.NumberOfTasks != 1 || summary.DoneTasks != 0 {
+			return errors.New(fmt.Sprintf("Summary of project 1 not matching: %+v", summary))
+		}
+		if summary.TotalProcessPoints != 10 || summary.DoneProcessPoints != 0 {
+			return errors.New("Process points of project 1 not set correctly")
+		}
+		lastActivity := time.Date(2021, 2, 13, 5, 16, 55, 150015000, time.UTC)
+		if summary.LastActivity == nil || !summary.LastActivity.Equal(lastActivity) {
+			return errors.New(fmt.Sprintf("Last activity of project 1 should be the latest comment (%s) but was %v", lastActivity, summary.LastActivity))
+		}
+
+		summary = page.Summaries[1]
+		if summary.Id != "2" || summary.Name != "Project 2" || summary.Owner != "Maria" || summary.NumberOfUsers != 6 || summary.NumberOfTasks != 5 || summary.DoneTasks != 1 {
+			return errors.New(fmt.Sprintf("Summary of project 2 not matching: %+v", summary))
+		}
+		if summary.TotalProcessPoints != 308 || summary.DoneProcessPoints != 154 {
+			return errors.New("Process points of project 2 not set correctly")
+		}
+		if summary.LastActivity != nil {
+			return errors.New(fmt.Sprintf("Project 2 should have no activity but was %s", summary.LastActivity))
+		}
+
+		page, err = s.GetSummaries("Maria", SortByProgress, true, 0, DefaultSummaryLimit)
+		if err != nil {
+			return err
+		}
+		if page.Summaries[0].Id != "2" || page.Summaries[1].Id != "1" {
+			return errors.New("Summaries should be sorted by progress")
+		}
+
+		page, err = s.GetSummaries("Maria", SortByName, false, 1, 1)
+		if err != nil {
+			return err
+		}
+		if page.Total != 2 || len(page.Summaries) != 1 || page.Summaries[0].Id != "2" {
+			return errors.New("Second page should only contain project 2")
+		}
+
+		var requestError *util.InvalidRequestError
+		_, err = s.GetSummaries("Maria", SummarySort("foo"), false, 0, DefaultSummaryLimit)
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Unknown sort attribute should be an invalid request: %v", err))
+		}
+
+		_, err = s.GetSummaries("Maria", SortById, false, -1, DefaultSummaryLimit)
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Negative offset should be an invalid request: %v", err))
+		}
+
+		_, err = s.GetSummaries("Maria", SortById, false, 0, MaxSummaryLimit+1)
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Limit above the maximum should be an invalid request: %v", err))
+		}
+
+		return nil
+	})
+}
+
 func TestGetProjectByTask(t *testing.T) {
 	h.Run(t, func() error {
 		project, err := s.GetProjectByTask("4")
@@ -166,7 +357,7 @@ func TestAddWithTasks(t *testing.T) {
 
 		t := task.DraftDto{
 			MaxProcessPoints: 100,
-			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[1,0]]]},\"properties\":null}",
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[0.01,0],[0.01,0.01],[0,0]]]},\"properties\":null}",
 		}
 
 		newProject, err := s.AddProjectWithTasks(&p, []task.DraftDto{t})
@@ -532,6 +723,410 @@ func TestRemoveDifferentUserNotAllowed(t *testing.T) {
 	})
 }
 
+func TestSetRole(t *testing.T) {
+	h.Run(t, func() error {
+		p, err := s.SetRole("2", "John", permission.Moderator, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Owner should be able to set role: %s", err.Error()))
+		}
+
+		var roleOfJohn permission.Role
+		for _, m := range p.Members {
+			if m.UserId == "John" {
+				roleOfJohn = m.Role
+			}
+		}
+		if roleOfJohn != permission.Moderator {
+			return errors.New(fmt.Sprintf("John should be moderator but has role '%s'", roleOfJohn))
+		}
+
+		// Moderators are allowed to remove members but no other moderators
+		_, err = s.RemoveUser("2", "John", "Carl")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Moderator should be able to remove member: %s", err.Error()))
+		}
+
+		_, err = s.SetRole("2", "Anna", permission.Moderator, "John")
+		if err == nil {
+			return errors.New("Only the owner should be able to set roles")
+		}
+
+		_, err = s.SetRole("2", "Anna", permission.Moderator, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Owner should be able to set role: %s", err.Error()))
+		}
+
+		_, err = s.RemoveUser("2", "John", "Anna")
+		if err == nil {
+			return errors.New("Moderator should not be able to remove other moderator")
+		}
+
+		// Viewers are not allowed to change tasks
+		_, err = s.SetRole("2", "Clara", permission.Viewer, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Owner should be able to set role: %s", err.Error()))
+		}
+
+		_, err = taskService.AssignUser("4", "Clara")
+		if err == nil {
+			return errors.New("Viewer should not be able to be assigned to a task")
+		}
+
+		// Invalid role changes
+		var requestError *util.InvalidRequestError
+		_, err = s.SetRole("2", "John", permission.Owner, "Maria")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Setting the owner role should be an invalid request: %v", err))
+		}
+
+		_, err = s.SetRole("2", "John", "FOOBAR", "Maria")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Setting an unknown role should be an invalid request: %v", err))
+		}
+
+		_, err = s.SetRole("2", "Maria", permission.Member, "Maria")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Changing the role of the owner should be an invalid request: %v", err))
+		}
+
+		_, err = s.SetRole("2", "Peter", permission.Member, "Maria")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Setting the role of a non-member should be an invalid request: %v", err))
+		}
+
+		return nil
+	})
+}
+
+func TestTransferOwnership(t *testing.T) {
+	h.Run(t, func() error {
+		var permissionError *permission.PermissionError
+		_, err := s.TransferOwnership("2", "John", "Anna")
+		if !errors.As(err, &permissionError) {
+			return errors.New(fmt.Sprintf("Only the owner should be allowed to transfer the ownership: %v", err))
+		}
+
+		var requestError *util.InvalidRequestError
+		_, err = s.TransferOwnership("2", "Peter", "Maria")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Non-member becoming owner should be an invalid request: %v", err))
+		}
+
+		_, err = s.TransferOwnership("2", "Maria", "Maria")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Transferring the ownership to the owner should be an invalid request: %v", err))
+		}
+
+		p, err := s.TransferOwnership("2", "John", "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Transferring ownership should work: %s", err.Error()))
+		}
+
+		if p.Owner != "John" {
+			return errors.New(fmt.Sprintf("John should be the new owner but owner is '%s'", p.Owner))
+		}
+
+		for _, m := range p.Members {
+			if m.UserId == "Maria" && m.Role != permission.Moderator {
+				return errors.New(fmt.Sprintf("Previous owner should be moderator but has role '%s'", m.Role))
+			}
+		}
+
+		// Old owner lost the owner permissions
+		err = s.DeleteProject("2", "Maria")
+		if err == nil {
+			return errors.New("Previous owner should not be able to delete the project")
+		}
+
+		return nil
+	})
+}
+
+func TestAddTasksToExistingProject(t *testing.T) {
+	h.Run(t, func() error {
+		draft := task.DraftDto{
+			MaxProcessPoints: 10,
+			ProcessPoints:    4,
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[0.01,0],[0.01,0.01],[0,0]]]},\"properties\":null}",
+		}
+
+		_, err := s.AddTasks("2", []task.DraftDto{draft}, "John")
+		if err == nil {
+			return errors.New("Only the owner should be able to add tasks")
+		}
+
+		_, err = s.AddTasks("2", []task.DraftDto{}, "Maria")
+		if err == nil {
+			return errors.New("Adding no tasks should not work")
+		}
+
+		invalidDraft := task.DraftDto{
+			MaxProcessPoints: 10,
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Point\",\"coordinates\":[0,0]},\"properties\":null}",
+		}
+		_, err = s.AddTasks("2", []task.DraftDto{invalidDraft}, "Maria")
+		if err == nil {
+			return errors.New("Adding tasks with invalid geometry should not work")
+		}
+
+		p, err := s.AddTasks("2", []task.DraftDto{draft}, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Adding tasks should work: %s", err.Error()))
+		}
+
+		if len(p.Tasks) != 6 {
+			return errors.New(fmt.Sprintf("Project should have 6 tasks but has %d", len(p.Tasks)))
+		}
+		if p.TotalProcessPoints != 318 || p.DoneProcessPoints != 158 {
+			return errors.New(fmt.Sprintf("Process points not updated: %d/%d", p.DoneProcessPoints, p.TotalProcessPoints))
+		}
+
+		var requestError *util.InvalidRequestError
+		config.Conf.MaxTasksPerProject = 6
+		_, err = s.AddTasks("2", []task.DraftDto{draft}, "Maria")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Adding tasks beyond the maximum amount of tasks should be an invalid request: %v", err))
+		}
+
+		return nil
+	})
+}
+
+func TestSetBoundary(t *testing.T) {
+	h.Run(t, func() error {
+		// Clockwise and therefore repaired
+		boundary := "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[0,1],[1,1],[1,0],[0,0]]]},\"properties\":null}"
+
+		_, err := s.SetBoundary("2", boundary, "John")
+		if err == nil {
+			return errors.New("Only the owner or a moderator should be able to set the boundary")
+		}
+
+		_, err = s.SetBoundary("2", "{\"type\":\"Point\",\"coordinates\":[0,0]}", "Maria")
+		if err == nil {
+			return errors.New("Setting a point as boundary should not work")
+		}
+
+		_, err = s.SetBoundary("2", "{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[1,1],[1,0],[0,1],[0,0]]]}", "Maria")
+		if err == nil {
+			return errors.New("Setting a self-intersecting boundary should not work")
+		}
+
+		p, err := s.SetBoundary("2", boundary, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Setting the boundary should work: %s", err.Error()))
+		}
+
+		expectedBoundary := "{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}"
+		if p.Boundary != expectedBoundary {
+			return errors.New(fmt.Sprintf("Boundary should be '%s' but was '%s'", expectedBoundary, p.Boundary))
+		}
+
+		p, err = s.GetProject("2", "John")
+		if err != nil {
+			return err
+		}
+		if p.Boundary != expectedBoundary {
+			return errors.New(fmt.Sprintf("Stored boundary should be '%s' but was '%s'", expectedBoundary, p.Boundary))
+		}
+
+		p, err = s.SetBoundary("2", "", "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Removing the boundary should work: %s", err.Error()))
+		}
+		if p.Boundary != "" {
+			return errors.New(fmt.Sprintf("Boundary should be removed but was '%s'", p.Boundary))
+		}
+
+		return nil
+	})
+}
+
+func TestJoinPublicProject(t *testing.T) {
+	h.Run(t, func() error {
+		var permissionError *permission.PermissionError
+		_, err := s.JoinProject("1", "Carl")
+		if !errors.As(err, &permissionError) {
+			return errors.New(fmt.Sprintf("Joining a non-public project should not be allowed: %v", err))
+		}
+
+		_, err = tx.Exec("UPDATE projects SET public=true WHERE id=1")
+		if err != nil {
+			return err
+		}
+
+		p, err := s.JoinProject("1", "Carl")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Joining a public project should work: %s", err.Error()))
+		}
+		if p.Users[len(p.Users)-1] != "Carl" || p.Members[len(p.Members)-1].Role != permission.Member {
+			return errors.New(fmt.Sprintf("Carl should be a member of the project: %v", p.Users))
+		}
+
+		_, err = s.JoinProject("1", "Carl")
+		if err == nil {
+			return errors.New("Joining a project twice should not be possible")
+		}
+
+		return nil
+	})
+}
+
+func TestInvitation(t *testing.T) {
+	h.Run(t, func() error {
+		_, err := s.CreateInvitation("2", "John")
+		if err == nil {
+			return errors.New("Normal members should not be able to create invitations")
+		}
+
+		invitationDto, err := s.CreateInvitation("2", "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Owner should be able to create invitations: %s", err.Error()))
+		}
+		if !invitationDto.ValidUntil.After(time.Now()) {
+			return errors.New(fmt.Sprintf("Invitation should be valid in the future but is valid until %s", invitationDto.ValidUntil))
+		}
+
+		p, err := s.RedeemInvitation(invitationDto.Token, "Peter")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Redeeming the invitation should work: %s", err.Error()))
+		}
+		if p.Id != "2" || p.Users[len(p.Users)-1] != "Peter" {
+			return errors.New(fmt.Sprintf("Peter should be a member of project 2: %v", p.Users))
+		}
+
+		// Token can be used multiple times
+		_, err = s.RedeemInvitation(invitationDto.Token, "Otto")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Redeeming the invitation twice should work: %s", err.Error()))
+		}
+
+		// Already a member
+		_, err = s.RedeemInvitation(invitationDto.Token, "Otto")
+		var requestError *util.InvalidRequestError
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Redeeming the invitation as member should be an invalid request but was: %v", err))
+		}
+
+		nonce, err := s.store.getInvitationNonce("2")
+		if err != nil {
+			return err
+		}
+
+		// Manipulated token with the secret of a different project
+		manipulatedToken, err := json.Marshal(&invitation{
+			ProjectId:  "3",
+			ValidUntil: invitationDto.ValidUntil.Unix(),
+			Secret:     createInvitationSecret("2", invitationDto.ValidUntil.Unix(), nonce),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = s.RedeemInvitation(base64.URLEncoding.EncodeToString(manipulatedToken), "Otto")
+		if err == nil {
+			return errors.New("Redeeming a manipulated invitation should not work")
+		}
+
+		// Expired token
+		nonce, err = s.store.getInvitationNonce("1")
+		if err != nil {
+			return err
+		}
+		expiredToken, err := createInvitationToken("1", time.Now().Add(-time.Minute).Unix(), nonce)
+		if err != nil {
+			return err
+		}
+		_, err = s.RedeemInvitation(expiredToken, "Otto")
+		if err == nil {
+			return errors.New("Redeeming an expired invitation should not work")
+		}
+
+		// Removing a user revokes all invitations, so that the user can't join again
+		_, err = s.RemoveUser("2", "Maria", "Peter")
+		if err != nil {
+			return err
+		}
+		_, err = s.RedeemInvitation(invitationDto.Token, "Peter")
+		if err == nil {
+			return errors.New("Redeeming an invitation after removing a user should not work")
+		}
+
+		return nil
+	})
+}
+
+func TestGetStatistics(t *testing.T) {
+	h.Run(t, func() error {
+		_, err := taskService.SetProcessPoints("3", 70, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Setting process points should work: %s", err.Error()))
+		}
+
+		// John sets 40 points, the owner lowers them to 10, which counts as John's loss and not as the owner's
+		_, err = taskService.AssignUser("4", "John")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Assigning user should work: %s", err.Error()))
+		}
+		_, err = taskService.SetProcessPoints("4", 40, "John")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Setting process points should work: %s", err.Error()))
+		}
+		_, err = taskService.UpdateTask("4", &task.UpdateDto{MaxProcessPoints: 10}, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Updating task should work: %s", err.Error()))
+		}
+
+		statistics, err := s.GetStatistics("2", task.IntervalDay, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Getting statistics should work: %s", err.Error()))
+		}
+
+		if statistics.NumberOfTasks != 5 || statistics.UntouchedTasks != 0 || statistics.InProgressTasks != 3 || statistics.DoneTasks != 2 {
+			return errors.New(fmt.Sprintf("Task states not correct: %v", statistics))
+		}
+
+		if statistics.AssignedTasks != 3 {
+			return errors.New(fmt.Sprintf("Expected 3 assigned tasks but got %d", statistics.AssignedTasks))
+		}
+
+		if len(statistics.Users) != 6 {
+			return errors.New(fmt.Sprintf("Expected statistics for 6 users but got %d", len(statistics.Users)))
+		}
+
+		for _, u := range statistics.Users {
+			if u.UserId == "Maria" && (u.ProcessPoints != 20 || u.TouchedTasks != 1 || u.AssignedTasks != 1) {
+				return errors.New(fmt.Sprintf("Statistics of Maria not correct: %v", u))
+			}
+			if u.UserId == "John" && (u.ProcessPoints != 10 || u.TouchedTasks != 1 || u.AssignedTasks != 1) {
+				return errors.New(fmt.Sprintf("Statistics of John not correct: %v", u))
+			}
+			if u.UserId == "Anna" && (u.ProcessPoints != 0 || u.TouchedTasks != 0 || u.AssignedTasks != 0) {
+				return errors.New(fmt.Sprintf("Statistics of Anna not correct: %v", u))
+			}
+		}
+
+		if len(statistics.Progress) != 1 || statistics.Progress[0].ProcessPoints != 30 {
+			return errors.New(fmt.Sprintf("Progress not correct: %v", statistics.Progress))
+		}
+
+		// Unknown interval
+		var requestError *util.InvalidRequestError
+		_, err = s.GetStatistics("2", "year", "Maria")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Unknown interval should be an invalid request: %v", err))
+		}
+
+		// Non-member
+		var permissionError *permission.PermissionError
+		_, err = s.GetStatistics("2", task.IntervalDay, "Peter")
+		if !errors.As(err, &permissionError) {
+			return errors.New(fmt.Sprintf("Non-member should not be allowed to get statistics: %v", err))
+		}
+
+		return nil
+	})
+}
+
 func TestDeleteProject(t *testing.T) {
 	h.Run(t, func() error {
 		id := "1" // owned by "Peter"
@@ -585,7 +1180,7 @@ func TestUpdate(t *testing.T) {
 		newName := "flubby dubby"
 		newDescription := "flubby dubby\n foo bar"
 		newJosmDataSource := Overpass
-		project, err := s.Update("1", newName, newDescription, newJosmDataSource, "Peter")
+		project, err := s.Update("1", newName, newDescription, newJosmDataSource, 48, true, true, "Peter")
 		if err != nil {
 			return errors.New(fmt.Sprintf("Error updating project wasn't expected: %s", err))
 		}
@@ -604,10 +1199,19 @@ func TestUpdate(t *testing.T) {
 		if project.JosmDataSource != newJosmDataSource {
 			return errors.New(fmt.Sprintf("New JOSM data source doesn't match with expected one: %s != %s", oldProject.JosmDataSource, newJosmDataSource))
 		}
+		if project.AssignmentTimeout != 48 {
+			return errors.New(fmt.Sprintf("New assignment timeout doesn't match with expected one: %d != 48", project.AssignmentTimeout))
+		}
+		if !project.NeedsValidation {
+			return errors.New("Project should need validation after update")
+		}
+		if !project.Public {
+			return errors.New("Project should be public after update")
+		}
 
 		// With newline
 		newNewlineName := "foo\nbar\nwhatever"
-		project, err = s.Update("1", newNewlineName, newDescription, newJosmDataSource, "Peter")
+		project, err = s.Update("1", newNewlineName, newDescription, newJosmDataSource, 48, true, true, "Peter")
 		if err != nil {
 			return errors.New(fmt.Sprintf("Error updating name wasn't expected: %s", err))
 		}
@@ -625,22 +1229,28 @@ func TestUpdate(t *testing.T) {
 		}
 
 		// With non-owner (Maria)
-		_, err = s.Update("1", "skfgkf", "sadkfzh", OSM, "Maria")
+		_, err = s.Update("1", "skfgkf", "sadkfzh", OSM, 0, false, false, "Maria")
 		if err == nil {
 			return errors.New("Updating name should not be possible for non-owner user Maria")
 		}
 
 		// Empty name
-		_, err = s.Update("1", "  ", "adsfkjg", OSM, "Peter")
+		_, err = s.Update("1", "  ", "adsfkjg", OSM, 0, false, false, "Peter")
 		if err == nil {
 			return errors.New("Updating name should not be possible with empty name")
 		}
 
+		// Negative assignment timeout
+		_, err = s.Update("1", "name", "adsfkjg", OSM, -1, false, false, "Peter")
+		if err == nil {
+			return errors.New("Updating project should not be possible with negative assignment timeout")
+		}
+
 		// Too long description
 		config.Conf.MaxDescriptionLength = 10 // lower the border for test purposes
 		newDescription = "This is some too long description"
 
-		_, err = s.Update("1", "name", newDescription, OSM, "Peter")
+		_, err = s.Update("1", "name", newDescription, OSM, 0, false, false, "Peter")
 		if err == nil {
 			return errors.New(fmt.Sprintf("Updating project description should not work. Allowed description length %d but was %d", config.Conf.MaxDescriptionLength, len(newDescription)))
 		}
@@ -659,7 +1269,7 @@ func TestUpdateWithUnicodeDescription(t *testing.T) {
 		}
 
 		newDescription := "foo bar →→" // 10 characters but more than 10 bytes
-		project, err := s.Update(oldProject.Id, oldProject.Name, newDescription, oldProject.JosmDataSource, "Peter")
+		project, err := s.Update(oldProject.Id, oldProject.Name, newDescription, oldProject.JosmDataSource, oldProject.AssignmentTimeout, oldProject.NeedsValidation, oldProject.Public, "Peter")
 		if err != nil {
 			return errors.New(fmt.Sprintf("Error updating project wasn't expected: %s", err))
 		}
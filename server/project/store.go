@@ -6,6 +6,8 @@ import (
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"stm/comment"
+	"stm/geometry"
+	"stm/permission"
 	"stm/task"
 	"stm/util"
 	"strconv"
@@ -15,40 +17,165 @@ import (
 // Helper struct to read raw data from database. The "Project" struct has higher-level structure (e.g. arrays), which we
 // don't have in the database columns.
 type projectRow struct {
-	id             int
-	name           string
-	users          []string
-	owner          string
-	description    string
-	creationDate   *time.Time
-	commentListId  string
-	josmDataSource JosmDataSource
+	id                int
+	name              string
+	description       string
+	creationDate      *time.Time
+	commentListId     string
+	josmDataSource    JosmDataSource
+	assignmentTimeout int
+	needsValidation   bool
+	public            bool
+	boundary          sql.NullString
 }
 
 type store struct {
 	*util.Logger
 	tx           *sql.Tx
 	table        string
+	memberTable  string
 	taskStore    *task.Store
 	commentStore *comment.Store
 }
 
+var (
+	// The boundary is stored as PostGIS geometry and returned as GeoJSON geometry
+	returnValues = "id, name, description, creation_date, comment_list_id, josm_data_source, assignment_timeout, needs_validation, public, ST_AsGeoJSON(boundary)"
+)
+
 func getStore(tx *sql.Tx, logger *util.Logger, taskStore *task.Store, commentStore *comment.Store) *store {
 	return &store{
 		Logger:       logger,
 		tx:           tx,
 		table:        "projects",
+		memberTable:  "project_members",
 		taskStore:    taskStore,
 		commentStore: commentStore,
 	}
 }
 
 func (s *store) getAllProjectsOfUser(userId string) ([]*Project, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE $1 = ANY(users)", s.table)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id IN (SELECT project_id FROM %s WHERE user_id = $1) ORDER BY id", returnValues, s.table, s.memberTable)
+	return s.queryProjects(query, userId)
+}
+
+// getProjectsOfUserInBoundingBox returns all projects of the user whose boundary or tasks intersect the bounding box.
+func (s *store) getProjectsOfUserInBoundingBox(userId string, bbox *geometry.BoundingBox) ([]*Project, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s p
+WHERE id IN (SELECT project_id FROM %s WHERE user_id = $1)
+	AND (
+		ST_Intersects(boundary, ST_MakeEnvelope($2, $3, $4, $5, 4326))
+		OR EXISTS(SELECT 1 FROM %s t WHERE t.project_id = p.id AND ST_Intersects(t.geometry, ST_MakeEnvelope($2, $3, $4, $5, 4326)))
+	)
+ORDER BY id`, returnValues, s.table, s.memberTable, s.taskStore.Table)
+	return s.queryProjects(query, userId, bbox.MinLon, bbox.MinLat, bbox.MaxLon, bbox.MaxLat)
+}
+
+// getSummariesOfUser returns one page of project summaries of the user. The figures are determined by the database
+// without loading any tasks.
+func (s *store) getSummariesOfUser(userId string, sort SummarySort, descending bool, offset int, limit int) ([]*Summary, error) {
+	sortColumns := map[SummarySort]string{
+		SortById:           "p.id",
+		SortByName:         "LOWER(p.name)",
+		SortByCreationDate: "p.creation_date",
+		SortByLastActivity: "activity.last_activity",
+		SortByProgress:     "task_figures.done_process_points::float / NULLIF(task_figures.total_process_points, 0)",
+	}
+	sortColumn, ok := sortColumns[sort]
+	if !ok {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("unknown sort attribute '%s'", sort))}
+	}
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+
+	query := fmt.Sprintf(`SELECT p.id, p.name, o.user_id, member_figures.number_of_users, task_figures.number_of_tasks, task_figures.done_tasks,
+	task_figures.total_process_points, task_figures.done_process_points, p.creation_date, activity.last_activity
+FROM %s p
+	JOIN %s o ON o.project_id = p.id AND o.role = $2,
+	LATERAL (
+		SELECT COUNT(*) AS number_of_users FROM %s WHERE project_id = p.id
+	) member_figures,
+	LATERAL (
+		SELECT COUNT(*) AS number_of_tasks,
+			COUNT(*) FILTER (WHERE process_points = max_process_points) AS done_tasks,
+			COALESCE(SUM(max_process_points), 0) AS total_process_points,
+			COALESCE(SUM(process_points), 0) AS done_process_points
+		FROM %s WHERE project_id = p.id
+	) task_figures,
+	LATERAL (
+		SELECT GREATEST(
+			(SELECT MAX(creation_date) FROM task_events WHERE project_id = p.id),
+			(SELECT MAX(creation_date) FROM comments WHERE comment_list_id = p.comment_list_id
+				OR comment_list_id IN (SELECT comment_list_id FROM %s WHERE project_id = p.id))
+		) AS last_activity
+	) activity
+WHERE p.id IN (SELECT project_id FROM %s WHERE user_id = $1)
+ORDER BY %s %s NULLS LAST, p.id
+OFFSET $3 LIMIT $4;`, s.table, s.memberTable, s.memberTable, s.taskStore.Table, s.taskStore.Table, s.memberTable, sortColumn, direction)
+	s.LogQuery(query, userId, permission.Owner, offset, limit)
+
+	rows, err := s.tx.Query(query, userId, permission.Owner, offset, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error executing query to get project summaries of user %s", userId)
+	}
+	defer rows.Close()
+
+	summaries := make([]*Summary, 0)
+	for rows.Next() {
+		var summary Summary
+		var id int
+		err = rows.Scan(&id, &summary.Name, &summary.Owner, &summary.NumberOfUsers, &summary.NumberOfTasks, &summary.DoneTasks, &summary.TotalProcessPoints, &summary.DoneProcessPoints, &summary.CreationDate, &summary.LastActivity)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan row into project summary")
+		}
+
+		summary.Id = strconv.Itoa(id)
+		if summary.CreationDate != nil {
+			t := summary.CreationDate.UTC()
+			summary.CreationDate = &t
+		}
+		if summary.LastActivity != nil {
+			t := summary.LastActivity.UTC()
+			summary.LastActivity = &t
+		}
+
+		summaries = append(summaries, &summary)
+	}
 
+	return summaries, nil
+}
+
+// getNumberOfProjectsOfUser returns the number of projects the user is a member of.
+func (s *store) getNumberOfProjectsOfUser(userId string) (int, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE user_id = $1;", s.memberTable)
 	s.LogQuery(query, userId)
 
 	rows, err := s.tx.Query(query, userId)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error executing query to get number of projects of user %s", userId)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return 0, errors.New("there is no next row or an error happened")
+	}
+
+	numberOfProjects := 0
+	err = rows.Scan(&numberOfProjects)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not scan row for number of projects")
+	}
+
+	return numberOfProjects, nil
+}
+
+// queryProjects executes the query and turns all resulting rows into projects with members, tasks and comments.
+func (s *store) queryProjects(query string, params ...interface{}) ([]*Project, error) {
+	s.LogQuery(query, params...)
+
+	rows, err := s.tx.Query(query, params...)
 	if err != nil {
 		return nil, errors.Wrap(err, "error executing query")
 	}
@@ -70,29 +197,32 @@ func (s *store) getAllProjectsOfUser(userId string) ([]*Project, error) {
 		return nil, err
 	}
 
-	// Add task-IDs and comments to projects
-	for i, project := range projects {
-		err = s.addTasksToProject(project)
-		if err != nil {
-			return nil, err
-		}
+	// Add members, tasks and comments to all projects at once instead of several queries per project
+	err = s.addMembersToProjects(projects)
+	if err != nil {
+		return nil, err
+	}
 
-		err = s.addCommentsToProject(project, projectRows[i])
-		if err != nil {
-			return nil, err
-		}
+	err = s.addTasksToProjects(projects)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.addCommentsToProjects(projects, projectRows)
+	if err != nil {
+		return nil, err
 	}
 
 	return projects, nil
 }
 
 func (s *store) getProject(projectId string) (*Project, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id=$1", s.table)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id=$1", returnValues, s.table)
 	return s.execQuery(query, projectId)
 }
 
 func (s *store) getProjectOfTask(taskId string) (*Project, error) {
-	query := fmt.Sprintf("SELECT p.* FROM %s p, %s t WHERE $1 = t.id AND t.project_id = p.id", s.table, s.taskStore.Table)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = (SELECT project_id FROM %s WHERE id = $1)", returnValues, s.table, s.taskStore.Table)
 	return s.execQuery(query, taskId)
 }
 
@@ -103,11 +233,28 @@ func (s *store) addProject(draft *DraftDto, creationDate time.Time) (*Project, e
 		return nil, err
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (name, description, users, owner, creation_date, comment_list_id, josm_data_source) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING *", s.table)
-	params := []interface{}{draft.Name, draft.Description, pq.Array(draft.Users), draft.Owner, creationDate, commentListId, draft.JosmDataSource}
+	query := fmt.Sprintf("INSERT INTO %s (name, description, creation_date, comment_list_id, josm_data_source, assignment_timeout, needs_validation, public, boundary) VALUES($1, $2, $3, $4, $5, $6, $7, $8, ST_GeomFromGeoJSON(NULLIF($9, ''))) RETURNING %s", s.table, returnValues)
+	params := []interface{}{draft.Name, draft.Description, creationDate, commentListId, draft.JosmDataSource, draft.AssignmentTimeout, draft.NeedsValidation, draft.Public, draft.Boundary}
 
 	s.LogQuery(query, params...)
-	project, _, err := s.execQueryWithoutTasks(query, params...)
+	project, _, err := s.execQueryWithoutMembers(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range draft.Users {
+		role := permission.Member
+		if u == draft.Owner {
+			role = permission.Owner
+		}
+
+		err = s.addMember(project.Id, u, role)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.addMembersToProject(project)
 	if err != nil {
 		return nil, err
 	}
@@ -118,35 +265,69 @@ func (s *store) addProject(draft *DraftDto, creationDate time.Time) (*Project, e
 	return project, nil
 }
 
-func (s *store) addUser(projectId string, userIdToAdd string) (*Project, error) {
-	originalProject, err := s.getProject(projectId)
+func (s *store) addUser(projectId string, userIdToAdd string, role permission.Role) (*Project, error) {
+	err := s.addMember(projectId, userIdToAdd, role)
 	if err != nil {
-		s.Err("error getting project with ID '%s'", projectId)
 		return nil, err
 	}
 
-	newUsers := append(originalProject.Users, userIdToAdd)
-
-	query := fmt.Sprintf("UPDATE %s SET users=$1 WHERE id=$2 RETURNING *", s.table)
-	return s.execQuery(query, pq.Array(newUsers), projectId)
+	return s.getProject(projectId)
 }
 
 func (s *store) removeUser(projectId string, userIdToRemove string) (*Project, error) {
-	originalProject, err := s.getProject(projectId)
+	query := fmt.Sprintf("DELETE FROM %s WHERE project_id=$1 AND user_id=$2", s.memberTable)
+	s.LogQuery(query, projectId, userIdToRemove)
+
+	_, err := s.tx.Exec(query, projectId, userIdToRemove)
 	if err != nil {
-		s.Err("error getting project with ID '%s'", projectId)
-		return nil, err
+		return nil, errors.Wrapf(err, "error removing user %s from project %s", userIdToRemove, projectId)
 	}
 
-	remainingUsers := make([]string, 0)
-	for _, u := range originalProject.Users {
-		if u != userIdToRemove {
-			remainingUsers = append(remainingUsers, u)
-		}
+	return s.getProject(projectId)
+}
+
+func (s *store) setRole(projectId string, userId string, role permission.Role) (*Project, error) {
+	query := fmt.Sprintf("UPDATE %s SET role=$3 WHERE project_id=$1 AND user_id=$2", s.memberTable)
+	s.LogQuery(query, projectId, userId, role)
+
+	_, err := s.tx.Exec(query, projectId, userId, role)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error setting role of user %s in project %s to %s", userId, projectId, role)
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET users=$1 WHERE id=$2 RETURNING *", s.table)
-	return s.execQuery(query, pq.Array(remainingUsers), projectId)
+	return s.getProject(projectId)
+}
+
+// transferOwnership makes the new owner the owner of the project. The previous owner stays in the project as moderator.
+func (s *store) transferOwnership(projectId string, previousOwnerId string, newOwnerId string) (*Project, error) {
+	// The previous owner has to be demoted first, because there's only one owner per project allowed
+	query := fmt.Sprintf("UPDATE %s SET role=$2 WHERE project_id=$1 AND user_id=$3", s.memberTable)
+
+	s.LogQuery(query, projectId, permission.Moderator, previousOwnerId)
+	_, err := s.tx.Exec(query, projectId, permission.Moderator, previousOwnerId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error removing ownership of user %s in project %s", previousOwnerId, projectId)
+	}
+
+	s.LogQuery(query, projectId, permission.Owner, newOwnerId)
+	_, err = s.tx.Exec(query, projectId, permission.Owner, newOwnerId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error making user %s owner of project %s", newOwnerId, projectId)
+	}
+
+	return s.getProject(projectId)
+}
+
+func (s *store) addMember(projectId string, userId string, role permission.Role) error {
+	query := fmt.Sprintf("INSERT INTO %s (project_id, user_id, role) VALUES($1, $2, $3)", s.memberTable)
+	s.LogQuery(query, projectId, userId, role)
+
+	_, err := s.tx.Exec(query, projectId, userId, role)
+	if err != nil {
+		return errors.Wrapf(err, "error adding user %s to project %s", userId, projectId)
+	}
+
+	return nil
 }
 
 func (s *store) delete(projectId string) error {
@@ -156,9 +337,52 @@ func (s *store) delete(projectId string) error {
 	return err
 }
 
-func (s *store) update(projectId string, newName string, newDescription string, newJosmDataSource JosmDataSource) (*Project, error) {
-	query := fmt.Sprintf("UPDATE %s SET name=$2, description=$3, josm_data_source=$4 WHERE id=$1 RETURNING *", s.table)
-	return s.execQuery(query, projectId, newName, newDescription, newJosmDataSource)
+func (s *store) update(projectId string, newName string, newDescription string, newJosmDataSource JosmDataSource, newAssignmentTimeout int, newNeedsValidation bool, newPublic bool) (*Project, error) {
+	query := fmt.Sprintf("UPDATE %s SET name=$2, description=$3, josm_data_source=$4, assignment_timeout=$5, needs_validation=$6, public=$7 WHERE id=$1 RETURNING %s", s.table, returnValues)
+	return s.execQuery(query, projectId, newName, newDescription, newJosmDataSource, newAssignmentTimeout, newNeedsValidation, newPublic)
+}
+
+// setBoundary sets the GeoJSON geometry of the project area. An empty boundary removes the boundary of the project.
+func (s *store) setBoundary(projectId string, boundary string) (*Project, error) {
+	query := fmt.Sprintf("UPDATE %s SET boundary=ST_GeomFromGeoJSON(NULLIF($2, '')) WHERE id=$1 RETURNING %s", s.table, returnValues)
+	return s.execQuery(query, projectId, boundary)
+}
+
+// getInvitationNonce returns the current nonce all invitations to the project are signed with.
+func (s *store) getInvitationNonce(projectId string) (string, error) {
+	query := fmt.Sprintf("SELECT invitation_nonce FROM %s WHERE id = $1;", s.table)
+	s.LogQuery(query, projectId)
+
+	rows, err := s.tx.Query(query, projectId)
+	if err != nil {
+		return "", errors.Wrapf(err, "error executing query to get invitation nonce for project %s", projectId)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", errors.New(fmt.Sprintf("project %s does not exist", projectId))
+	}
+
+	nonce := ""
+	err = rows.Scan(&nonce)
+	if err != nil {
+		return "", errors.Wrap(err, "could not scan row for invitation nonce")
+	}
+
+	return nonce, nil
+}
+
+// setInvitationNonce replaces the nonce of the project, which makes all existing invitations to the project invalid.
+func (s *store) setInvitationNonce(projectId string, nonce string) error {
+	query := fmt.Sprintf("UPDATE %s SET invitation_nonce = $2 WHERE id = $1;", s.table)
+	s.LogQuery(query, projectId, nonce)
+
+	_, err := s.tx.Exec(query, projectId, nonce)
+	if err != nil {
+		return errors.Wrapf(err, "error setting invitation nonce of project %s", projectId)
+	}
+
+	return nil
 }
 
 func (s *store) getCommentListId(projectId string) (string, error) {
@@ -184,7 +408,22 @@ func (s *store) getCommentListId(projectId string) (string, error) {
 	return commentListId, nil
 }
 
+// execQueryWithoutTasks executes the query and adds the members to the resulting project but no tasks.
 func (s *store) execQueryWithoutTasks(query string, params ...interface{}) (*Project, *projectRow, error) {
+	project, projectRow, err := s.execQueryWithoutMembers(query, params...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = s.addMembersToProject(project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, projectRow, nil
+}
+
+func (s *store) execQueryWithoutMembers(query string, params ...interface{}) (*Project, *projectRow, error) {
 	rows, err := s.tx.Query(query, params...)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "could not run query")
@@ -232,7 +471,7 @@ func (s *store) execQuery(query string, params ...interface{}) (*Project, error)
 // rowToProject turns the current row into a Project object. This does not close the row.
 func (s *store) rowToProject(rows *sql.Rows) (*Project, *projectRow, error) {
 	var row projectRow
-	err := rows.Scan(&row.id, &row.name, &row.owner, &row.description, pq.Array(&row.users), &row.creationDate, &row.commentListId, &row.josmDataSource)
+	err := rows.Scan(&row.id, &row.name, &row.description, &row.creationDate, &row.commentListId, &row.josmDataSource, &row.assignmentTimeout, &row.needsValidation, &row.public, &row.boundary)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "could not scan rows")
 	}
@@ -241,10 +480,12 @@ func (s *store) rowToProject(rows *sql.Rows) (*Project, *projectRow, error) {
 
 	result.Id = strconv.Itoa(row.id)
 	result.Name = row.name
-	result.Users = row.users
-	result.Owner = row.owner
 	result.Description = row.description
 	result.JosmDataSource = row.josmDataSource
+	result.AssignmentTimeout = row.assignmentTimeout
+	result.NeedsValidation = row.needsValidation
+	result.Public = row.public
+	result.Boundary = row.boundary.String
 
 	if row.creationDate != nil {
 		t := row.creationDate.UTC()
@@ -254,6 +495,55 @@ func (s *store) rowToProject(rows *sql.Rows) (*Project, *projectRow, error) {
 	return &result, &row, nil
 }
 
+// addMembersToProject sets the members, the users and the owner of the project.
+func (s *store) addMembersToProject(project *Project) error {
+	return s.addMembersToProjects([]*Project{project})
+}
+
+// addMembersToProjects sets the members, the users and the owner of all given projects using one single query.
+func (s *store) addMembersToProjects(projects []*Project) error {
+	projectIds := make([]string, len(projects))
+	projectMap := make(map[string]*Project, len(projects))
+	for i, project := range projects {
+		projectIds[i] = project.Id
+		projectMap[project.Id] = project
+
+		project.Members = make([]*Member, 0)
+		project.Users = make([]string, 0)
+	}
+
+	if len(projects) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("SELECT project_id, user_id, role FROM %s WHERE project_id = ANY($1) ORDER BY id", s.memberTable)
+	s.LogQuery(query, projectIds)
+
+	rows, err := s.tx.Query(query, pq.Array(projectIds))
+	if err != nil {
+		return errors.Wrapf(err, "error executing query to get members of projects %v", projectIds)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var projectId string
+		var member Member
+		err = rows.Scan(&projectId, &member.UserId, &member.Role)
+		if err != nil {
+			return errors.Wrap(err, "could not scan row into member")
+		}
+
+		project := projectMap[projectId]
+		project.Members = append(project.Members, &member)
+		project.Users = append(project.Users, member.UserId)
+		if member.Role == permission.Owner {
+			project.Owner = member.UserId
+		}
+	}
+
+	return nil
+}
+
 func (s *store) addTasksToProject(project *Project) error {
 	tasks, err := s.taskStore.GetAllTasksOfProject(project.Id)
 	if err != nil {
@@ -266,6 +556,32 @@ func (s *store) addTasksToProject(project *Project) error {
 	return nil
 }
 
+// addTasksToProjects sets the tasks of all given projects. The number of queries doesn't depend on the number of
+// projects or tasks.
+func (s *store) addTasksToProjects(projects []*Project) error {
+	projectIds := make([]string, len(projects))
+	for i, project := range projects {
+		projectIds[i] = project.Id
+	}
+
+	tasks, err := s.taskStore.GetAllTasksOfProjects(projectIds)
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		projectTasks, ok := tasks[project.Id]
+		if !ok {
+			return errors.New(fmt.Sprintf("Tasks of project %s do not exist", project.Id))
+		}
+
+		project.Tasks = projectTasks
+	}
+	s.Log("Added tasks to projects %v", projectIds)
+
+	return nil
+}
+
 func (s *store) addCommentsToProject(project *Project, projectRow *projectRow) error {
 	comments, err := s.commentStore.GetComments(projectRow.commentListId)
 	if err != nil {
@@ -274,3 +590,22 @@ func (s *store) addCommentsToProject(project *Project, projectRow *projectRow) e
 	project.Comments = comments
 	return nil
 }
+
+// addCommentsToProjects sets the comments of all given projects using one single query.
+func (s *store) addCommentsToProjects(projects []*Project, projectRows []*projectRow) error {
+	commentListIds := make([]string, len(projectRows))
+	for i, projectRow := range projectRows {
+		commentListIds[i] = projectRow.commentListId
+	}
+
+	comments, err := s.commentStore.GetCommentsOfLists(commentListIds)
+	if err != nil {
+		return err
+	}
+
+	for i, project := range projects {
+		project.Comments = comments[projectRows[i].commentListId]
+	}
+
+	return nil
+}
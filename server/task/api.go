@@ -1,7 +1,45 @@
 package task
 
+import "stm/geometry"
+
 type DraftDto struct {
 	MaxProcessPoints int    `json:"maxProcessPoints"` // The maximum amount of process points of this task. Must be larger than zero.
 	ProcessPoints    int    `json:"processPoints"`    // The amount of process points that have been set by the user. It applies that "0 <= processPoints <= maxProcessPoints".
 	Geometry         string `json:"geometry"`         // A GeoJson feature with a polygon or multi-polygon geometry. If the feature properties contain the field "name", then this will be used as the name of the task.
 }
+
+type UpdateDto struct {
+	Name             string `json:"name"`             // The new name of the task. An empty name removes the name of the task.
+	MaxProcessPoints int    `json:"maxProcessPoints"` // The new maximum amount of process points. Must be larger than zero. Higher process points of the task are lowered to this value.
+	Geometry         string `json:"geometry"`         // Optional GeoJson feature with a polygon or multi-polygon geometry replacing the current geometry. The current geometry is kept when this is empty.
+}
+
+type SplitDto struct {
+	Geometry         string  `json:"geometry"`         // A GeoJson feature with a polygon or multi-polygon geometry. This is the area that should be divided into tasks.
+	Mode             string  `json:"mode"`             // The division mode. One of "squareGrid", "hexGrid", "triangleGrid" or "voronoi".
+	CellSize         float64 `json:"cellSize"`         // The size of the grid cells in meters. Must be larger than zero for all grid modes.
+	CellCount        int     `json:"cellCount"`        // The number of cells for the "voronoi" mode. Must be larger than zero for this mode.
+	MaxProcessPoints int     `json:"maxProcessPoints"` // The maximum amount of process points of the resulting task drafts. Must be larger than zero.
+}
+
+type TaskSplitDto struct {
+	Mode      string  `json:"mode"`      // The division mode. One of "squareGrid", "hexGrid", "triangleGrid" or "voronoi".
+	CellSize  float64 `json:"cellSize"`  // The size of the grid cells in meters. Must be larger than zero for all grid modes.
+	CellCount int     `json:"cellCount"` // The number of cells for the "voronoi" mode. Must be larger than zero for this mode.
+}
+
+type MergeDto struct {
+	TaskIds []string `json:"taskIds"` // The IDs of at least two tasks of the same project, which should be merged into one task.
+}
+
+type ListDto struct {
+	AssignedUser string                // Only tasks assigned to this user. Empty for tasks of all users.
+	Unassigned   bool                  // Only tasks without assigned user. Can't be combined with "AssignedUser".
+	State        ProgressState         // Only tasks in this state. Empty for tasks in all states.
+	HasComments  bool                  // Only tasks with at least one comment.
+	BoundingBox  *geometry.BoundingBox // Only tasks intersecting this bounding box. NIL for tasks everywhere.
+	Sort         TaskSort              // The attribute to sort the tasks by.
+	Descending   bool                  // Sort in descending instead of ascending order.
+	Cursor       string                // The cursor of the previous page. Empty for the first page.
+	Limit        int                   // The maximum number of tasks on the page. Must be between 1 and "MaxPageLimit".
+}
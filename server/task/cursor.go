@@ -0,0 +1,69 @@
+package task
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"stm/util"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// pageCursor marks the last task of a page. The next page starts with the task following this one in the requested
+// order. The cursor is only valid for the sort order it has been created for.
+type pageCursor struct {
+	Sort       TaskSort `json:"sort"`
+	Descending bool     `json:"descending"`
+	Value      string   `json:"value"` // The value of the sort attribute of the last task. Empty when sorting by ID.
+	TaskId     int      `json:"taskId"`
+}
+
+func newPageCursor(task *Task, sort TaskSort, descending bool) (*pageCursor, error) {
+	taskId, err := strconv.Atoi(task.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid ID of task %s", task.Id)
+	}
+
+	value := ""
+	switch sort {
+	case SortByName:
+		value = task.Name
+	case SortByProgress:
+		// Same calculation as in the database to get exactly the same value
+		value = strconv.FormatFloat(float64(task.ProcessPoints)/float64(task.MaxProcessPoints), 'g', -1, 64)
+	case SortByAssignedUser:
+		value = task.AssignedUser
+	}
+
+	return &pageCursor{
+		Sort:       sort,
+		Descending: descending,
+		Value:      value,
+		TaskId:     taskId,
+	}, nil
+}
+
+// encode turns the cursor into an URL-safe string.
+func (c *pageCursor) encode() (string, error) {
+	cursorJson, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to serialize cursor")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(cursorJson), nil
+}
+
+func decodePageCursor(value string) (*pageCursor, error) {
+	cursorJson, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, &util.InvalidRequestError{Err: errors.Wrap(err, "invalid cursor")}
+	}
+
+	var cursor pageCursor
+	err = json.Unmarshal(cursorJson, &cursor)
+	if err != nil {
+		return nil, &util.InvalidRequestError{Err: errors.Wrap(err, "invalid cursor")}
+	}
+
+	return &cursor, nil
+}
@@ -1,6 +1,11 @@
 package task
 
-import "stm/comment"
+import (
+	"fmt"
+	"stm/comment"
+	"strings"
+	"time"
+)
 
 type Task struct {
 	Id               string `json:"id"`               // The ID of the task.
@@ -9,6 +14,116 @@ type Task struct {
 	MaxProcessPoints int    `json:"maxProcessPoints"` // The maximum amount of process points of this task. Is larger than zero.
 	Geometry         string `json:"geometry"`         // A GeoJson feature of the task wit a polygon or multipolygon geometry. Will never be NULL or empty.
 	// TODO Use "Id" as suffix?
-	AssignedUser string            `json:"assignedUser"` // The user-ID of the user who is currently assigned to this task. Will never be NULL but might be empty.
-	Comments     []comment.Comment `json:"comments"`
+	AssignedUser    string            `json:"assignedUser"` // The user-ID of the user who is currently assigned to this task. Will never be NULL but might be empty.
+	Comments        []comment.Comment `json:"comments"`
+	ValidationState ValidationState   `json:"validationState"` // The state of the task within the validation workflow. Only relevant for projects that need validation.
+	Mapper          string            `json:"mapper"`          // The user-ID of the user who set the maximum process points. Only set for tasks that need review or are validated.
+	Validator       string            `json:"validator"`       // The user-ID of the user who validated the task. Only set for validated tasks.
+}
+
+type ValidationState string
+
+const (
+	Mapping     ValidationState = "MAPPING"
+	NeedsReview ValidationState = "NEEDS_REVIEW"
+	Validated   ValidationState = "VALIDATED"
+)
+
+type EventType string
+
+const (
+	EventAssignment    EventType = "assignment"
+	EventUnassignment  EventType = "unassignment"
+	EventProcessPoints EventType = "processPoints"
+	EventValidation    EventType = "validation"
+	EventRejection     EventType = "rejection"
+	EventSplit         EventType = "split"            // The task has been replaced by new tasks or is one of these new tasks.
+	EventMerge         EventType = "merge"            // The task has been merged into a new task or is the new merged task.
+	EventMaxPoints     EventType = "maxProcessPoints" // The process points have been lowered to the new maximum process points of the task.
+)
+
+type Event struct {
+	Id                    string     `json:"id"`                    // The ID of the event.
+	TaskId                string     `json:"taskId"`                // The ID of the task this event belongs to.
+	Type                  EventType  `json:"type"`                  // The kind of change. One of "assignment", "unassignment", "processPoints", "validation", "rejection", "split", "merge" and "maxProcessPoints".
+	UserId                string     `json:"userId"`                // The user-ID of the user who performed the change.
+	AssignedUser          string     `json:"assignedUser"`          // The user-ID of the user who has been assigned or unassigned. For splits and merges it's the user assigned to the task.
+	PreviousProcessPoints int        `json:"previousProcessPoints"` // The process points before the change. Only set for process point changes, rejections, splits and merges.
+	ProcessPoints         int        `json:"processPoints"`         // The process points after the change. Only set for process point changes, rejections, splits and merges.
+	CreationDate          *time.Time `json:"creationDate"`          // The time this change happened.
+}
+
+// ProgressState describes how far the mapping of a task is.
+type ProgressState string
+
+const (
+	StateNotStarted ProgressState = "notStarted" // No process points have been set.
+	StateInProgress ProgressState = "inProgress" // Some but not all process points have been set.
+	StateDone       ProgressState = "done"       // All process points have been set.
+)
+
+// TaskSort is the attribute by which the tasks of a page are sorted. Tasks with equal values are sorted by their ID.
+type TaskSort string
+
+const (
+	SortById           TaskSort = "id"
+	SortByName         TaskSort = "name"
+	SortByProgress     TaskSort = "progress"
+	SortByAssignedUser TaskSort = "assignedUser"
+)
+
+const (
+	DefaultPageLimit = 100  // Number of tasks on a page, when no limit is requested.
+	MaxPageLimit     = 1000 // Maximum number of tasks on one page.
+)
+
+type Page struct {
+	Tasks      []*Task `json:"tasks"`      // The tasks of this page.
+	NextCursor string  `json:"nextCursor"` // The cursor to get the next page with. Empty when this is the last page.
+}
+
+type ProgressInterval string
+
+const (
+	IntervalDay   ProgressInterval = "day"
+	IntervalWeek  ProgressInterval = "week"
+	IntervalMonth ProgressInterval = "month"
+)
+
+type Contribution struct {
+	UserId        string `json:"userId"`        // The user-ID of the contributing user.
+	ProcessPoints int    `json:"processPoints"` // Sum of all process point changes made by this user. Points reset by rejections or lowered maximum process points are subtracted from the user who set them. Might be negative when the user reduced process points.
+	TouchedTasks  int    `json:"touchedTasks"`  // Number of tasks this user has been assigned to or has set process points on.
+}
+
+type ProgressEntry struct {
+	Date          *time.Time `json:"date"`          // The start of the interval in UTC.
+	ProcessPoints int        `json:"processPoints"` // Sum of all process point changes within this interval.
+}
+
+// Overlap is an area, where two tasks of a project overlap each other.
+type Overlap struct {
+	TaskIds  []string // The IDs of the two overlapping tasks.
+	Geometry string   // The overlapping area as GeoJSON geometry.
+	Area     float64  // The size of the overlapping area in square meters.
+}
+
+// Gap is an area within a project, which isn't covered by any task.
+type Gap struct {
+	Geometry string  // The uncovered area as GeoJSON geometry.
+	Area     float64 // The size of the uncovered area in square meters.
+}
+
+// DraftError contains the problems of all invalid task drafts, so that they can be fixed at once. It's caused by invalid
+// input and not by a problem of the server.
+type DraftError struct {
+	Problems []string // One message per problem, each starting with the affected task.
+}
+
+func (e *DraftError) Error() string {
+	return fmt.Sprintf("invalid tasks: %s", strings.Join(e.Problems, "; "))
+}
+
+func (e *DraftError) add(index int, problem string) {
+	e.Problems = append(e.Problems, fmt.Sprintf("task %d: %s", index, problem))
 }
@@ -6,16 +6,23 @@ import (
 	geojson "github.com/paulmach/go.geojson"
 	"github.com/pkg/errors"
 	"stm/comment"
+	"stm/config"
+	"stm/geometry"
 	"stm/permission"
 	"stm/util"
 	"strings"
+	"time"
 )
 
+// Overlaps and gaps smaller than this (in square meters) are rounding errors rather than actual problems.
+const minCoverageProblemArea = 1.0
+
 type Service struct {
 	*util.Logger
 	store           *Store
 	permissionStore *permission.Store
 	commentService  *comment.Service
+	commentStore    *comment.Store
 }
 
 func Init(tx *sql.Tx, logger *util.Logger, permissionStore *permission.Store, commentService *comment.Service, commentStore *comment.Store) *Service {
@@ -24,6 +31,7 @@ func Init(tx *sql.Tx, logger *util.Logger, permissionStore *permission.Store, co
 		store:           GetStore(tx, logger, commentStore),
 		permissionStore: permissionStore,
 		commentService:  commentService,
+		commentStore:    commentStore,
 	}
 }
 
@@ -46,34 +54,107 @@ func (s *Service) GetTasks(projectId string) ([]*Task, error) {
 	return tasks, err
 }
 
-// AddTasks sets the ID of the tasks and adds them to the storage.
+// GetTasksOfProject returns one page of tasks of the project, which match the filters of the list DTO. The next page
+// can be requested with the cursor of the returned page. The requesting user must be a member of the project.
+func (s *Service) GetTasksOfProject(projectId string, listDto *ListDto, requestingUserId string) (*Page, error) {
+	err := s.permissionStore.VerifyMembershipProject(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if listDto.Limit <= 0 || listDto.Limit > MaxPageLimit {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("Limit must be between 1 and %d but was %d", MaxPageLimit, listDto.Limit))}
+	}
+	if listDto.Sort != SortById && listDto.Sort != SortByName && listDto.Sort != SortByProgress && listDto.Sort != SortByAssignedUser {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("unknown sort attribute '%s'", listDto.Sort))}
+	}
+	if listDto.State != "" && listDto.State != StateNotStarted && listDto.State != StateInProgress && listDto.State != StateDone {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("unknown state '%s'", listDto.State))}
+	}
+	if listDto.AssignedUser != "" && listDto.Unassigned {
+		return nil, &util.InvalidRequestError{Err: errors.New("Tasks can't be assigned and unassigned at the same time")}
+	}
+
+	var cursor *pageCursor
+	if listDto.Cursor != "" {
+		cursor, err = decodePageCursor(listDto.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != listDto.Sort || cursor.Descending != listDto.Descending {
+			return nil, &util.InvalidRequestError{Err: errors.New("Cursor belongs to a different sort order")}
+		}
+	}
+
+	// One more task tells whether there's a next page
+	tasks, err := s.store.getTasksPage(projectId, listDto, cursor, listDto.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		Tasks: tasks,
+	}
+
+	if len(tasks) > listDto.Limit {
+		page.Tasks = tasks[:listDto.Limit]
+
+		nextCursor, err := newPageCursor(page.Tasks[len(page.Tasks)-1], listDto.Sort, listDto.Descending)
+		if err != nil {
+			return nil, err
+		}
+
+		page.NextCursor, err = nextCursor.encode()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// AddTasks sets the ID of the tasks and adds them to the storage. The geometries of the tasks are validated and
+// repaired if possible. All problems of all tasks are returned at once as DraftError. The added tasks are returned in
+// the same order as the given drafts.
 func (s *Service) AddTasks(newTasks []DraftDto, projectId string) ([]*Task, error) {
-	for _, t := range newTasks {
+	drafts := make([]DraftDto, len(newTasks))
+	draftError := &DraftError{}
+
+	for i, t := range newTasks {
+		drafts[i] = t
+
 		if t.MaxProcessPoints < 1 {
-			return nil, errors.New(fmt.Sprintf("Maximum process points must be at least 1 (%d)", t.MaxProcessPoints))
+			draftError.add(i, fmt.Sprintf("maximum process points must be at least 1 (%d)", t.MaxProcessPoints))
+			continue
 		}
 
-		// Check for valid geojson
-		feature, err := geojson.UnmarshalFeature([]byte(t.Geometry))
+		feature, err := s.parseTaskGeometry(t.Geometry)
 		if err != nil {
-			return nil, errors.Wrap(err, fmt.Sprintf("invalid GeoJSON: %s", t.Geometry))
+			draftError.add(i, err.Error())
+			continue
 		}
 
-		if feature.Type != "Feature" || feature.Geometry == nil {
-			s.Err("Invalid feature found: %#v", feature)
-			return nil, errors.New(fmt.Sprintf("task geometry is null, not a feature or doesn't contain a polygon: %s", t.Geometry))
+		repaired, err := validateTaskGeometry(feature)
+		if err != nil {
+			draftError.add(i, err.Error())
+			continue
 		}
 
-		if !(feature.Geometry.Type == geojson.GeometryPolygon || feature.Geometry.Type == geojson.GeometryMultiPolygon) {
-			s.Err("Invalid geometry type found: %#v", feature)
-			return nil, errors.New(fmt.Sprintf("task geometry has invalid type: %s. Only \"%s\" and \"%s\" allowed", t.Geometry, geojson.GeometryPolygon, geojson.GeometryMultiPolygon))
+		if repaired {
+			repairedGeometry, err := feature.MarshalJSON()
+			if err != nil {
+				return nil, errors.Wrap(err, "error marshalling repaired task geometry")
+			}
+			drafts[i].Geometry = string(repairedGeometry)
+			s.Log("Repaired geometry of task draft %d", i)
 		}
+	}
 
-		// Delete id property to not be confused with the id of the task
-		delete(feature.Properties, "id")
+	if len(draftError.Problems) > 0 {
+		return nil, draftError
 	}
 
-	tasks, err := s.store.addTasks(newTasks, projectId)
+	tasks, err := s.store.addTasks(drafts, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +164,79 @@ func (s *Service) AddTasks(newTasks []DraftDto, projectId string) ([]*Task, erro
 	return tasks, nil
 }
 
+// SplitArea divides the area of the given DTO into task drafts. The drafts are not stored, they can be used to create a
+// new project.
+func (s *Service) SplitArea(splitDto *SplitDto) ([]DraftDto, error) {
+	if splitDto.MaxProcessPoints < 1 {
+		return nil, errors.New(fmt.Sprintf("Maximum process points must be at least 1 (%d)", splitDto.MaxProcessPoints))
+	}
+
+	feature, err := geojson.UnmarshalFeature([]byte(splitDto.Geometry))
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("invalid GeoJSON: %s", splitDto.Geometry))
+	}
+
+	polygons, err := geometry.ToPolygons(feature.Geometry)
+	if err != nil {
+		return nil, err
+	}
+
+	cells, err := s.divide(polygons, splitDto.Mode, splitDto.CellSize, splitDto.CellCount)
+	if err != nil {
+		return nil, err
+	}
+
+	drafts := make([]DraftDto, len(cells))
+	for i, cell := range cells {
+		cellGeometry, err := geojson.NewPolygonFeature(cell).MarshalJSON()
+		if err != nil {
+			return nil, errors.Wrap(err, "error marshalling cell geometry")
+		}
+
+		drafts[i] = DraftDto{
+			MaxProcessPoints: splitDto.MaxProcessPoints,
+			ProcessPoints:    0,
+			Geometry:         string(cellGeometry),
+		}
+	}
+	s.Log("Split area into %d task drafts using mode %s", len(drafts), splitDto.Mode)
+
+	return drafts, nil
+}
+
+// divide creates the cells for the given polygons and makes sure the result doesn't exceed the maximum number of tasks
+// per project.
+func (s *Service) divide(polygons [][][][]float64, divisionMode string, cellSize float64, cellCount int) ([][][][]float64, error) {
+	mode := geometry.DivisionMode(divisionMode)
+
+	// Creating the cells is expensive (especially the voronoi cells), so reject requests creating too many cells before
+	// doing any work. The estimation is exact for voronoi cells and an upper bound for the grids.
+	estimatedCellCount := geometry.EstimateCellCount(polygons, mode, cellSize, cellCount)
+	if estimatedCellCount > config.Conf.MaxTasksPerProject {
+		return nil, errors.New(fmt.Sprintf("Division would create too many tasks (up to %d), maximum %d tasks allowed", estimatedCellCount, config.Conf.MaxTasksPerProject))
+	}
+
+	cells, err := geometry.Divide(polygons, mode, cellSize, cellCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cells) == 0 {
+		return nil, errors.New("Division resulted in no tasks")
+	}
+	if len(cells) > config.Conf.MaxTasksPerProject {
+		return nil, errors.New(fmt.Sprintf("Division resulted in %d tasks but maximum %d tasks allowed", len(cells), config.Conf.MaxTasksPerProject))
+	}
+
+	return cells, nil
+}
+
 func (s *Service) AssignUser(taskId, userId string) (*Task, error) {
+	err := s.permissionStore.VerifyWriteAccessTask(taskId, userId)
+	if err != nil {
+		return nil, err
+	}
+
 	task, err := s.store.getTask(taskId)
 	if err != nil {
 		return nil, err
@@ -94,7 +247,12 @@ func (s *Service) AssignUser(taskId, userId string) (*Task, error) {
 		return nil, errors.New(fmt.Sprintf("task %s has already an assigned userId, cannot overwrite", task.Id))
 	}
 
-	task, err = s.store.assignUser(taskId, userId)
+	task, err = s.store.assignUser(taskId, userId, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.addEvent(taskId, EventAssignment, userId, userId, 0, 0, time.Now().UTC())
 	if err != nil {
 		return nil, err
 	}
@@ -103,23 +261,79 @@ func (s *Service) AssignUser(taskId, userId string) (*Task, error) {
 	return task, nil
 }
 
+// UnassignUser removes the assigned user from the task. This is allowed for the assigned user, the owner and the
+// moderators of the project.
 func (s *Service) UnassignUser(taskId, requestingUserId string) (*Task, error) {
 	err := s.permissionStore.VerifyCanUnassign(taskId, requestingUserId)
 	if err != nil {
 		return nil, err
 	}
 
-	task, err := s.store.unassignUser(taskId)
+	task, err := s.store.getTask(taskId)
+	if err != nil {
+		return nil, err
+	}
+	unassignedUser := task.AssignedUser
+
+	task, err = s.store.unassignUser(taskId)
 	if err != nil {
 		return nil, err
 	}
-	s.Log("Unassigned user %s from task %s", requestingUserId, taskId)
+
+	// The requesting user might be the owner or a moderator, so the unassigned user is recorded separately
+	err = s.store.addEvent(taskId, EventUnassignment, requestingUserId, unassignedUser, 0, 0, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	s.Log("User %s unassigned user %s from task %s", requestingUserId, unassignedUser, taskId)
 
 	return task, nil
 }
 
+// UnassignExpiredTasks removes the assigned user from all tasks, which have been assigned for longer than the
+// assignment timeout of their project. A comment on each task explains the automatic unassignment.
+func (s *Service) UnassignExpiredTasks() ([]*Task, error) {
+	taskIds, err := s.store.getTaskIdsWithExpiredAssignment(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*Task, 0, len(taskIds))
+	for _, taskId := range taskIds {
+		task, err := s.store.getTask(taskId)
+		if err != nil {
+			return nil, err
+		}
+		assignedUser := task.AssignedUser
+
+		commentDraft := &comment.DraftDto{
+			Text: fmt.Sprintf("The assignment of user %s expired, the user has been unassigned automatically.", assignedUser),
+		}
+		err = s.addComment(taskId, commentDraft, comment.SystemAuthorId)
+		if err != nil {
+			return nil, err
+		}
+
+		task, err = s.store.unassignUser(taskId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.store.addEvent(taskId, EventUnassignment, comment.SystemAuthorId, assignedUser, 0, 0, time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+
+		s.Log("Unassigned user %s from task %s due to expired assignment", assignedUser, taskId)
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
 // SetProcessPoints updates the process points on task "id". When "needsAssignedUser" is true on the project, this
-// function also checks, whether the assigned user is equal to the requesting User.
+// function also checks, whether the assigned user is equal to the requesting User. When the project needs validation,
+// a task reaching its maximum process points needs to be reviewed and validated tasks can't be changed anymore.
 func (s *Service) SetProcessPoints(taskId string, newPoints int, requestingUserId string) (*Task, error) {
 	needsAssignment, err := s.permissionStore.AssignmentInTaskNeeded(taskId)
 	if err != nil {
@@ -131,7 +345,7 @@ func (s *Service) SetProcessPoints(taskId string, newPoints int, requestingUserI
 			return nil, err
 		}
 	} else { // when no assignment is needed, the requesting user at least needs to be a member
-		err := s.permissionStore.VerifyMembershipTask(taskId, requestingUserId)
+		err := s.permissionStore.VerifyWriteAccessTask(taskId, requestingUserId)
 		if err != nil {
 			s.Err("user not a member of the project, the task %s belongs to", taskId)
 			return nil, err
@@ -148,24 +362,608 @@ func (s *Service) SetProcessPoints(taskId string, newPoints int, requestingUserI
 		return nil, errors.New("process points out of range")
 	}
 
+	needsValidation, err := s.permissionStore.ValidationInTaskNeeded(taskId)
+	if err != nil {
+		return nil, err
+	}
+	if needsValidation && task.ValidationState == Validated {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("task %s has already been validated, process points cannot be changed", taskId))}
+	}
+
+	previousPoints := task.ProcessPoints
+	previousState := task.ValidationState
+
 	task, err = s.store.setProcessPoints(taskId, newPoints)
 	if err != nil {
 		return nil, err
 	}
+
+	err = s.store.addEvent(taskId, EventProcessPoints, requestingUserId, "", previousPoints, newPoints, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
 	s.Log("Set process points of task %s to %d", taskId, newPoints)
 
+	if needsValidation {
+		if newPoints == task.MaxProcessPoints && previousState != NeedsReview {
+			task, err = s.store.setValidationState(taskId, NeedsReview, requestingUserId, "")
+		} else if newPoints < task.MaxProcessPoints && previousState == NeedsReview {
+			task, err = s.store.setValidationState(taskId, Mapping, "", "")
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.Log("Validation state of task %s is %s", taskId, task.ValidationState)
+	}
+
+	return task, nil
+}
+
+// CheckCoverage determines the areas where tasks of the project overlap each other and the areas which aren't covered
+// by any task. Gaps are searched within the given boundary, the boundary of the project or, if there's none, within the
+// convex hull of all tasks.
+// The result contains one feature per problem with the properties "type" ("overlap" or "gap"), "area" (in square
+// meters) and, for overlaps, the "taskIds" of the overlapping tasks. The requesting user must be a member of the project.
+func (s *Service) CheckCoverage(projectId string, boundary *geojson.Geometry, requestingUserId string) (*geojson.FeatureCollection, error) {
+	err := s.permissionStore.VerifyMembershipProject(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	boundaryString := ""
+	if boundary != nil {
+		boundaryBytes, err := boundary.MarshalJSON()
+		if err != nil {
+			return nil, errors.Wrap(err, "error marshalling boundary")
+		}
+		boundaryString = string(boundaryBytes)
+	}
+
+	overlaps, err := s.store.getOverlaps(projectId, minCoverageProblemArea)
+	if err != nil {
+		return nil, err
+	}
+
+	gaps, err := s.store.getGaps(projectId, boundaryString, minCoverageProblemArea)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := geojson.NewFeatureCollection()
+	for _, o := range overlaps {
+		feature, err := toCoverageFeature(o.Geometry, "overlap", o.Area)
+		if err != nil {
+			return nil, err
+		}
+		feature.SetProperty("taskIds", o.TaskIds)
+		collection.AddFeature(feature)
+	}
+	for _, g := range gaps {
+		feature, err := toCoverageFeature(g.Geometry, "gap", g.Area)
+		if err != nil {
+			return nil, err
+		}
+		collection.AddFeature(feature)
+	}
+	s.Log("Found %d overlaps and %d gaps in project %s", len(overlaps), len(gaps), projectId)
+
+	return collection, nil
+}
+
+func toCoverageFeature(geometryString string, problemType string, area float64) (*geojson.Feature, error) {
+	featureGeometry, err := geojson.UnmarshalGeometry([]byte(geometryString))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshal %s geometry", problemType)
+	}
+
+	feature := geojson.NewFeature(featureGeometry)
+	feature.SetProperty("type", problemType)
+	feature.SetProperty("area", area)
+
+	return feature, nil
+}
+
+// ValidateTask marks the task, which needs to be reviewed, as validated. Every member of the project except the mapper
+// of the task is allowed to do that.
+func (s *Service) ValidateTask(taskId string, requestingUserId string) (*Task, error) {
+	task, err := s.getTaskToReview(taskId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err = s.store.setValidationState(taskId, Validated, task.Mapper, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.addEvent(taskId, EventValidation, requestingUserId, "", task.ProcessPoints, task.ProcessPoints, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	s.Log("User %s validated task %s", requestingUserId, taskId)
+
+	return task, nil
+}
+
+// RejectTask sends the task, which needs to be reviewed, back to mapping. The comment explaining the rejection is
+// added to the task and the process points are reset. Every member of the project except the mapper of the task is
+// allowed to do that.
+func (s *Service) RejectTask(taskId string, draftDto *comment.DraftDto, requestingUserId string) (*Task, error) {
+	if strings.TrimSpace(draftDto.Text) == "" {
+		return nil, &util.InvalidRequestError{Err: errors.New("a comment explaining the rejection is required")}
+	}
+
+	task, err := s.getTaskToReview(taskId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+	previousPoints := task.ProcessPoints
+
+	err = s.addComment(taskId, draftDto, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.store.setProcessPoints(taskId, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err = s.store.setValidationState(taskId, Mapping, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.addEvent(taskId, EventRejection, requestingUserId, "", previousPoints, 0, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	s.Log("User %s sent task %s back to mapping", requestingUserId, taskId)
+
+	return task, nil
+}
+
+// getTaskToReview returns the task when it needs to be reviewed and the user is allowed to review it.
+func (s *Service) getTaskToReview(taskId string, requestingUserId string) (*Task, error) {
+	needsValidation, err := s.permissionStore.ValidationInTaskNeeded(taskId)
+	if err != nil {
+		return nil, err
+	}
+	if !needsValidation {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("the project of task %s does not need validation", taskId))}
+	}
+
+	err = s.permissionStore.VerifyCanValidate(taskId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.store.getTask(taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.ValidationState != NeedsReview {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("task %s does not need to be reviewed (state %s)", taskId, task.ValidationState))}
+	}
+
+	return task, nil
+}
+
+// GetHistory returns all assignments, unassignments and process point changes of the task from oldest to newest. The
+// requesting user must be a member of the project.
+func (s *Service) GetHistory(taskId string, requestingUserId string) ([]*Event, error) {
+	err := s.permissionStore.VerifyMembershipTask(taskId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.getEvents(taskId)
+}
+
+// SplitTask divides the task into new tasks using the given division mode. The new tasks replace the original task,
+// they get its process points, its validation state and a copy of its comments. The requesting user must be the owner
+// or a moderator of the project.
+func (s *Service) SplitTask(taskId string, splitDto *TaskSplitDto, requestingUserId string) ([]*Task, error) {
+	projectId, err := s.store.getProjectIdOfTasks([]string{taskId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.permissionStore.VerifyModeration(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.store.getTask(taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	feature, err := geojson.UnmarshalFeature([]byte(task.Geometry))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshal geometry of task %s", taskId)
+	}
+
+	polygons, err := geometry.ToPolygons(feature.Geometry)
+	if err != nil {
+		return nil, err
+	}
+
+	cells, err := s.divide(polygons, splitDto.Mode, splitDto.CellSize, splitDto.CellCount)
+	if err != nil {
+		return nil, &util.InvalidRequestError{Err: err}
+	}
+
+	numberOfTasks, err := s.store.getNumberOfTasks(projectId)
+	if err != nil {
+		return nil, err
+	}
+	if numberOfTasks-1+len(cells) > config.Conf.MaxTasksPerProject {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("Splitting task %s would result in %d tasks, maximum %d tasks allowed", taskId, numberOfTasks-1+len(cells), config.Conf.MaxTasksPerProject))}
+	}
+
+	// Every new task keeps the progress of the original task, so a finished task results in finished tasks
+	drafts := make([]DraftDto, len(cells))
+	for i, cell := range cells {
+		cellFeature := geojson.NewPolygonFeature(cell)
+		if task.Name != "" {
+			cellFeature.SetProperty("name", fmt.Sprintf("%s - %d", task.Name, i+1))
+		}
+
+		cellGeometry, err := cellFeature.MarshalJSON()
+		if err != nil {
+			return nil, errors.Wrap(err, "error marshalling cell geometry")
+		}
+
+		drafts[i] = DraftDto{
+			MaxProcessPoints: task.MaxProcessPoints,
+			ProcessPoints:    task.ProcessPoints,
+			Geometry:         string(cellGeometry),
+		}
+	}
+
+	newTaskIds, err := s.store.addTasksWithoutFetching(drafts, projectId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// The new tasks are as far in the validation workflow as the original task
+	if task.ValidationState != Mapping {
+		for _, newTaskId := range newTaskIds {
+			_, err = s.store.setValidationState(newTaskId, task.ValidationState, task.Mapper, task.Validator)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = s.replaceTasks([]*Task{task}, newTaskIds, EventSplit, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+	s.Log("Split task %s into %d new tasks %v", taskId, len(newTaskIds), newTaskIds)
+
+	return s.store.getTasks(newTaskIds)
+}
+
+// MergeTasks replaces the given tasks of one project by a single task with a multi-polygon geometry. The process points
+// of all tasks are summed up and the new task contains a copy of all their comments. The tasks must not be assigned to
+// different users, the merged task keeps the assignment. The requesting user must be the owner or a moderator of the
+// project.
+func (s *Service) MergeTasks(taskIds []string, requestingUserId string) (*Task, error) {
+	uniqueTaskIds := make([]string, 0)
+	seenTaskIds := make(map[string]bool)
+	for _, taskId := range taskIds {
+		if !seenTaskIds[taskId] {
+			seenTaskIds[taskId] = true
+			uniqueTaskIds = append(uniqueTaskIds, taskId)
+		}
+	}
+	if len(uniqueTaskIds) < 2 {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("At least two different tasks are needed to merge them (%v)", taskIds))}
+	}
+
+	projectId, err := s.store.getProjectIdOfTasks(uniqueTaskIds)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.permissionStore.VerifyModeration(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.store.getTasks(uniqueTaskIds)
+	if err != nil {
+		return nil, err
+	}
+
+	mergedDraft := DraftDto{}
+	assignedUser := ""
+	polygons := make([][][][]float64, 0)
+	var properties map[string]interface{}
+	for _, t := range tasks {
+		if t.AssignedUser != "" && assignedUser != "" && t.AssignedUser != assignedUser {
+			return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("tasks %v are assigned to different users, cannot merge them", uniqueTaskIds))}
+		}
+		if t.AssignedUser != "" {
+			assignedUser = t.AssignedUser
+		}
+
+		feature, err := geojson.UnmarshalFeature([]byte(t.Geometry))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not unmarshal geometry of task %s", t.Id)
+		}
+
+		taskPolygons, err := geometry.ToPolygons(feature.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		polygons = append(polygons, taskPolygons...)
+
+		// The merged task gets the properties (e.g. the name) of the first task having some
+		if properties == nil && len(feature.Properties) != 0 {
+			properties = feature.Properties
+		}
+
+		mergedDraft.MaxProcessPoints += t.MaxProcessPoints
+		mergedDraft.ProcessPoints += t.ProcessPoints
+	}
+
+	mergedFeature := geojson.NewMultiPolygonFeature(polygons...)
+	if properties != nil {
+		mergedFeature.Properties = properties
+	}
+
+	mergedGeometry, err := mergedFeature.MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling merged geometry")
+	}
+	mergedDraft.Geometry = string(mergedGeometry)
+
+	newTaskIds, err := s.store.addTasksWithoutFetching([]DraftDto{mergedDraft}, projectId, assignedUser)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewTask := getMergedValidationState(tasks)
+	if reviewTask.ValidationState != Mapping {
+		_, err = s.store.setValidationState(newTaskIds[0], reviewTask.ValidationState, reviewTask.Mapper, reviewTask.Validator)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.replaceTasks(tasks, newTaskIds, EventMerge, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+	s.Log("Merged tasks %v into new task %s", uniqueTaskIds, newTaskIds[0])
+
+	return s.store.getTask(newTaskIds[0])
+}
+
+// getMergedValidationState returns the task, whose validation state (including mapper and validator) the merged task
+// gets. This is the task being the least far in the validation workflow, e.g. a merged task is only validated when all
+// its tasks are validated.
+func getMergedValidationState(tasks []*Task) *Task {
+	order := map[ValidationState]int{Mapping: 0, NeedsReview: 1, Validated: 2}
+
+	result := tasks[0]
+	for _, t := range tasks[1:] {
+		if order[t.ValidationState] < order[result.ValidationState] {
+			result = t
+		}
+	}
+
+	return result
+}
+
+// replaceTasks copies the comments of all old tasks to every new task and removes the old tasks afterwards. The
+// replacement is recorded as events of the given type, so that the history and the progress of the project stay
+// correct.
+func (s *Service) replaceTasks(oldTasks []*Task, newTaskIds []string, eventType EventType, requestingUserId string) error {
+	now := time.Now().UTC()
+
+	oldTaskIds := toTaskIds(oldTasks)
+	allTaskIds := append(append([]string{}, oldTaskIds...), newTaskIds...)
+	commentListIds, err := s.store.getCommentListIds(allTaskIds)
+	if err != nil {
+		return err
+	}
+
+	for _, oldTask := range oldTasks {
+		for _, newTaskId := range newTaskIds {
+			err = s.commentStore.CopyComments(commentListIds[oldTask.Id], commentListIds[newTaskId])
+			if err != nil {
+				return err
+			}
+		}
+
+		// The process points of the old task are gone
+		err = s.store.addEvent(oldTask.Id, eventType, requestingUserId, oldTask.AssignedUser, oldTask.ProcessPoints, 0, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	newTasks, err := s.store.getTasks(newTaskIds)
+	if err != nil {
+		return err
+	}
+
+	for _, newTask := range newTasks {
+		err = s.store.addEvent(newTask.Id, eventType, requestingUserId, newTask.AssignedUser, 0, newTask.ProcessPoints, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.store.delete(oldTaskIds)
+}
+
+// parseTaskGeometry checks that the geometry is a GeoJSON feature with a polygon or multi-polygon geometry. The "id"
+// property is removed from the returned feature to not be confused with the id of the task.
+func (s *Service) parseTaskGeometry(taskGeometry string) (*geojson.Feature, error) {
+	feature, err := geojson.UnmarshalFeature([]byte(taskGeometry))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid GeoJSON")
+	}
+
+	if feature.Type != "Feature" || feature.Geometry == nil {
+		s.Err("Invalid feature found: %#v", feature)
+		return nil, errors.New("task geometry is null, not a feature or doesn't contain a polygon")
+	}
+
+	if !(feature.Geometry.Type == geojson.GeometryPolygon || feature.Geometry.Type == geojson.GeometryMultiPolygon) {
+		s.Err("Invalid geometry type found: %#v", feature)
+		return nil, errors.New(fmt.Sprintf("task geometry has invalid type %s. Only \"%s\" and \"%s\" allowed", feature.Geometry.Type, geojson.GeometryPolygon, geojson.GeometryMultiPolygon))
+	}
+
+	delete(feature.Properties, "id")
+
+	return feature, nil
+}
+
+// validateTaskGeometry checks the polygons of the feature and their area against the configured limits. Repairable
+// problems are fixed within the given feature, in which case true is returned.
+func validateTaskGeometry(feature *geojson.Feature) (bool, error) {
+	validGeometry, repaired, err := geometry.ValidateGeometry(feature.Geometry, float64(config.Conf.MinTaskArea), float64(config.Conf.MaxTaskArea))
+	if err != nil {
+		return false, err
+	}
+
+	feature.Geometry = validGeometry
+	return repaired, nil
+}
+
+// UpdateTask changes the name, the maximum process points and optionally the geometry of the task. The requesting user
+// must be the owner or a moderator of the project. When the maximum process points drop below the current process
+// points, the process points are lowered to the new maximum. A task finished this way needs to be reviewed, when the
+// project needs validation.
+func (s *Service) UpdateTask(taskId string, dto *UpdateDto, requestingUserId string) (*Task, error) {
+	projectId, err := s.store.getProjectIdOfTasks([]string{taskId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.permissionStore.VerifyModeration(projectId, requestingUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if dto.MaxProcessPoints < 1 {
+		return nil, &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("Maximum process points must be at least 1 (%d)", dto.MaxProcessPoints))}
+	}
+
+	task, err := s.store.getTask(taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without new geometry, the current one is kept and only the name is changed
+	taskGeometry := dto.Geometry
+	if taskGeometry == "" {
+		taskGeometry = task.Geometry
+	}
+
+	feature, err := s.parseTaskGeometry(taskGeometry)
+	if err != nil {
+		return nil, &DraftError{Problems: []string{fmt.Sprintf("task %s: %s", taskId, err.Error())}}
+	}
+
+	// The current geometry has been checked when it was added, so only new geometries are validated
+	if dto.Geometry != "" {
+		_, err = validateTaskGeometry(feature)
+		if err != nil {
+			return nil, &DraftError{Problems: []string{fmt.Sprintf("task %s: %s", taskId, err.Error())}}
+		}
+	}
+
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		delete(feature.Properties, "name")
+	} else {
+		feature.SetProperty("name", name)
+	}
+
+	geometryBytes, err := feature.MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling task geometry")
+	}
+
+	previousPoints := task.ProcessPoints
+	previousState := task.ValidationState
+	newPoints := previousPoints
+	if newPoints > dto.MaxProcessPoints {
+		newPoints = dto.MaxProcessPoints
+	}
+
+	needsValidation, err := s.permissionStore.ValidationInTaskNeeded(taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err = s.store.update(taskId, dto.MaxProcessPoints, newPoints, string(geometryBytes))
+	if err != nil {
+		return nil, err
+	}
+	s.Log("Updated task %s", taskId)
+
+	if newPoints != previousPoints {
+		err = s.store.addEvent(taskId, EventMaxPoints, requestingUserId, "", previousPoints, newPoints, time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		s.Log("Lowered process points of task %s from %d to %d", taskId, previousPoints, newPoints)
+	}
+
+	if newPoints < task.MaxProcessPoints && previousState != Mapping {
+		// A task that isn't finished anymore, has to be mapped and reviewed again
+		task, err = s.store.setValidationState(taskId, Mapping, "", "")
+	} else if needsValidation && newPoints == task.MaxProcessPoints && previousState == Mapping {
+		// Same as when setting the process points: A finished task needs to be reviewed by someone else than the mapper
+		var mapper string
+		mapper, err = s.store.getLastMapper(taskId)
+		if err != nil {
+			return nil, err
+		}
+		if mapper == "" {
+			mapper = requestingUserId
+		}
+		task, err = s.store.setValidationState(taskId, NeedsReview, mapper, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if task.ValidationState != previousState {
+		s.Log("Validation state of task %s is %s", taskId, task.ValidationState)
+	}
+
 	return task, nil
 }
 
-// Delete will remove the given tasks, if the requestingUser is a member of the project these tasks are in.
-// WARNING: This method, unfortunately, doesn't check the task relation to project, so there might be broken references
-// left (from a project to a not existing task). So: USE WITH CARE!!!
-// This relates to the github issue https://github.com/hauke96/simple-task-manager/issues/33
+// Delete removes the given tasks including their comments. All tasks must belong to the same project, the requesting
+// user must be the owner or a moderator of this project and the project must still have at least one task afterwards.
 func (s *Service) Delete(taskIds []string, requestingUserId string) error {
-	err := s.permissionStore.VerifyMembershipTasks(taskIds, requestingUserId)
+	projectId, err := s.store.getProjectIdOfTasks(taskIds)
+	if err != nil {
+		return err
+	}
+
+	err = s.permissionStore.VerifyModeration(projectId, requestingUserId)
+	if err != nil {
+		return err
+	}
+
+	numberOfTasks, err := s.store.getNumberOfTasks(projectId)
 	if err != nil {
 		return err
 	}
+	if numberOfTasks <= len(taskIds) {
+		return &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("Deleting tasks %v would leave project %s without tasks", taskIds, projectId))}
+	}
 
 	err = s.store.delete(taskIds)
 	if err != nil {
@@ -176,7 +974,28 @@ func (s *Service) Delete(taskIds []string, requestingUserId string) error {
 	return nil
 }
 
+// AddComment adds the comment to the task, if the author has write access to the project of the task.
 func (s *Service) AddComment(taskId string, draftDto *comment.DraftDto, authorId string) error {
+	err := s.permissionStore.VerifyWriteAccessTask(taskId, authorId)
+	if err != nil {
+		return err
+	}
+
+	return s.addComment(taskId, draftDto, authorId)
+}
+
+// ImportComments adds the comments with their original authors and dates to the task. There are no permission checks,
+// so this must only be used when importing a project.
+func (s *Service) ImportComments(taskId string, comments []comment.Comment) error {
+	commentListId, err := s.store.getCommentListId(taskId)
+	if err != nil {
+		return err
+	}
+
+	return s.commentService.ImportComments(commentListId, comments)
+}
+
+func (s *Service) addComment(taskId string, draftDto *comment.DraftDto, authorId string) error {
 	commentListId, err := s.store.getCommentListId(taskId)
 	if err != nil {
 		return err
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"stm/comment"
 	"stm/config"
+	"stm/geometry"
 	"stm/permission"
 	"stm/test"
 	"stm/util"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hauke96/sigolo"
+	geojson "github.com/paulmach/go.geojson"
 	"github.com/pkg/errors"
 
 	_ "github.com/lib/pq" // Make driver "postgres" usable
@@ -84,12 +88,228 @@ func TestGetTasksOfUnknownProject(t *testing.T) {
 	})
 }
 
+func TestGetTasksOfProjectInBoundingBox(t *testing.T) {
+	h.Run(t, func() error {
+		listDto := &ListDto{
+			BoundingBox: &geometry.BoundingBox{MinLon: 9.944, MinLat: 53.562, MaxLon: 9.947, MaxLat: 53.565},
+			Sort:        SortById,
+			Limit:       DefaultPageLimit,
+		}
+
+		_, err := s.GetTasksOfProject("2", listDto, "Peter")
+		if err == nil {
+			return errors.New("Non-member should not be able to get tasks")
+		}
+
+		page, err := s.GetTasksOfProject("2", listDto, "John")
+		if err != nil {
+			return err
+		}
+		if len(page.Tasks) != 1 || page.Tasks[0].Id != "3" {
+			return errors.New(fmt.Sprintf("Only task 3 should be within bounding box but found %d tasks", len(page.Tasks)))
+		}
+
+		listDto.BoundingBox = &geometry.BoundingBox{MinLon: 20, MinLat: 20, MaxLon: 21, MaxLat: 21}
+		page, err = s.GetTasksOfProject("2", listDto, "John")
+		if err != nil {
+			return err
+		}
+		if len(page.Tasks) != 0 {
+			return errors.New("No task should be within bounding box")
+		}
+
+		listDto.BoundingBox = nil
+		page, err = s.GetTasksOfProject("2", listDto, "John")
+		if err != nil {
+			return err
+		}
+		if len(page.Tasks) != 5 || page.NextCursor != "" {
+			return errors.New(fmt.Sprintf("Without bounding box all 5 tasks should be returned but found %d tasks", len(page.Tasks)))
+		}
+
+		return nil
+	})
+}
+
+func TestGetTasksOfProjectWithFilters(t *testing.T) {
+	h.Run(t, func() error {
+		expectTasks := func(listDto *ListDto, expectedTaskIds ...string) error {
+			page, err := s.GetTasksOfProject("2", listDto, "John")
+			if err != nil {
+				return err
+			}
+
+			taskIds := make([]string, len(page.Tasks))
+			for i, t := range page.Tasks {
+				taskIds[i] = t.Id
+			}
+			if strings.Join(taskIds, ",") != strings.Join(expectedTaskIds, ",") {
+				return errors.New(fmt.Sprintf("Expected tasks %v but got %v for %+v", expectedTaskIds, taskIds, *listDto))
+			}
+			return nil
+		}
+
+		err := expectTasks(&ListDto{AssignedUser: "Maria", Sort: SortById, Limit: DefaultPageLimit}, "3")
+		if err != nil {
+			return err
+		}
+
+		err = expectTasks(&ListDto{Unassigned: true, Sort: SortById, Limit: DefaultPageLimit}, "2", "4", "6")
+		if err != nil {
+			return err
+		}
+
+		err = expectTasks(&ListDto{State: StateNotStarted, Sort: SortById, Limit: DefaultPageLimit}, "4")
+		if err != nil {
+			return err
+		}
+
+		err = expectTasks(&ListDto{State: StateInProgress, Unassigned: true, Sort: SortById, Limit: DefaultPageLimit}, "6")
+		if err != nil {
+			return err
+		}
+
+		err = expectTasks(&ListDto{State: StateDone, Sort: SortById, Limit: DefaultPageLimit}, "2")
+		if err != nil {
+			return err
+		}
+
+		err = expectTasks(&ListDto{HasComments: true, Sort: SortById, Limit: DefaultPageLimit})
+		if err != nil {
+			return err
+		}
+
+		err = s.AddComment("4", &comment.DraftDto{Text: "Some comment"}, "John")
+		if err != nil {
+			return err
+		}
+		err = expectTasks(&ListDto{HasComments: true, Sort: SortById, Limit: DefaultPageLimit}, "4")
+		if err != nil {
+			return err
+		}
+
+		err = expectTasks(&ListDto{Sort: SortByAssignedUser, Descending: true, Limit: DefaultPageLimit}, "3", "7", "6", "4", "2")
+		if err != nil {
+			return err
+		}
+
+		_, err = s.GetTasksOfProject("2", &ListDto{AssignedUser: "Maria", Unassigned: true, Sort: SortById, Limit: DefaultPageLimit}, "John")
+		if err == nil {
+			return errors.New("Assigned and unassigned tasks at the same time should not be possible")
+		}
+
+		_, err = s.GetTasksOfProject("2", &ListDto{State: "foo", Sort: SortById, Limit: DefaultPageLimit}, "John")
+		if err == nil {
+			return errors.New("Unknown state should not be allowed")
+		}
+
+		var requestError *util.InvalidRequestError
+		_, err = s.GetTasksOfProject("2", &ListDto{Sort: "foo", Limit: DefaultPageLimit}, "John")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Unknown sort attribute should be an invalid request: %v", err))
+		}
+
+		_, err = s.GetTasksOfProject("2", &ListDto{Sort: SortById, Limit: MaxPageLimit + 1}, "John")
+		if err == nil {
+			return errors.New("Limit above the maximum should not be allowed")
+		}
+
+		return nil
+	})
+}
+
+func TestGetTasksOfProjectWithCursor(t *testing.T) {
+	h.Run(t, func() error {
+		listDto := &ListDto{
+			Sort:       SortByProgress,
+			Descending: true,
+			Limit:      2,
+		}
+
+		// Progress of the tasks: 2 = 100%, 7 = 75%, 3 = 50%, 6 = 25%, 4 = 0%
+		expectedPages := [][]string{{"2", "7"}, {"3", "6"}, {"4"}}
+		for i, expectedTaskIds := range expectedPages {
+			page, err := s.GetTasksOfProject("2", listDto, "John")
+			if err != nil {
+				return err
+			}
+
+			if len(page.Tasks) != len(expectedTaskIds) {
+				return errors.New(fmt.Sprintf("Page %d should have %d tasks but had %d", i, len(expectedTaskIds), len(page.Tasks)))
+			}
+			for j, taskId := range expectedTaskIds {
+				if page.Tasks[j].Id != taskId {
+					return errors.New(fmt.Sprintf("Task %d of page %d should be %s but was %s", j, i, taskId, page.Tasks[j].Id))
+				}
+			}
+
+			isLastPage := i == len(expectedPages)-1
+			if isLastPage != (page.NextCursor == "") {
+				return errors.New(fmt.Sprintf("Only the last page should have no cursor but page %d had cursor '%s'", i, page.NextCursor))
+			}
+
+			listDto.Cursor = page.NextCursor
+		}
+
+		// Cursors can't be used with a different sort order
+		page, err := s.GetTasksOfProject("2", &ListDto{Sort: SortByProgress, Descending: true, Limit: 2}, "John")
+		if err != nil {
+			return err
+		}
+		_, err = s.GetTasksOfProject("2", &ListDto{Sort: SortByName, Cursor: page.NextCursor, Limit: 2}, "John")
+		if err == nil {
+			return errors.New("Cursor of different sort order should not be allowed")
+		}
+
+		var requestError *util.InvalidRequestError
+		_, err = s.GetTasksOfProject("2", &ListDto{Sort: SortById, Cursor: "foo", Limit: 2}, "John")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Invalid cursor should be an invalid request: %v", err))
+		}
+
+		return nil
+	})
+}
+
+func BenchmarkGetAllTasksOfProject(b *testing.B) {
+	h.Run(b, func() error {
+		// Project with 1000 tasks, the comments of all of them are loaded with one query
+		drafts := make([]DraftDto, 1000)
+		for i := range drafts {
+			lon := float64(i%100) * 0.01
+			lat := float64(i/100) * 0.01
+			drafts[i] = DraftDto{
+				MaxProcessPoints: 10,
+				Geometry:         fmt.Sprintf("{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[%f,%f],[%f,%f],[%f,%f],[%f,%f]]]},\"properties\":null}", lon, lat, lon+0.01, lat, lon+0.01, lat+0.01, lon, lat),
+			}
+		}
+
+		_, err := s.store.addTasksWithoutFetching(drafts, "3", "")
+		if err != nil {
+			return err
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tasks, err := s.store.GetAllTasksOfProject("3")
+			if err != nil {
+				return err
+			}
+			if len(tasks) != 1002 {
+				return errors.New(fmt.Sprintf("Expected 1002 tasks but got %d", len(tasks)))
+			}
+		}
+
+		return nil
+	})
+}
+
 func TestAddTasks(t *testing.T) {
 	h.Run(t, func() error {
 		rawTask := DraftDto{
 			MaxProcessPoints: 250,
 			ProcessPoints:    123,
-			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[1,0]]]},\"properties\":null}",
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[0.01,0],[0.01,0.01],[0,0]]]},\"properties\":null}",
 		}
 
 		addedTasks, err := s.AddTasks([]DraftDto{rawTask}, "1")
@@ -97,7 +317,11 @@ func TestAddTasks(t *testing.T) {
 			return err
 		}
 
-		addedTask := addedTasks[1] // [0] is the original task from the dummy data
+		if len(addedTasks) != 1 {
+			return errors.New(fmt.Sprintf("Expected only the added task but got %d tasks", len(addedTasks)))
+		}
+
+		addedTask := addedTasks[0]
 		if addedTask.AssignedUser != "" ||
 			addedTask.MaxProcessPoints != rawTask.MaxProcessPoints ||
 			addedTask.Geometry != rawTask.Geometry ||
@@ -114,12 +338,44 @@ func TestAddTasks(t *testing.T) {
 	})
 }
 
+func TestAddTasksKeepsPropertiesAndMultiPolygons(t *testing.T) {
+	h.Run(t, func() error {
+		rawTask := DraftDto{
+			MaxProcessPoints: 10,
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"MultiPolygon\",\"coordinates\":[[[[0,0],[0.01,0],[0.01,0.01],[0,0]]],[[[1,1],[1.01,1],[1.01,1.01],[1,1]]]]},\"properties\":{\"name\":\"Task A\",\"color\":\"red\"}}",
+		}
+
+		addedTasks, err := s.AddTasks([]DraftDto{rawTask}, "1")
+		if err != nil {
+			return err
+		}
+
+		addedTask := addedTasks[0]
+		if addedTask.Name != "Task A" {
+			return errors.New(fmt.Sprintf("Name should be 'Task A' but was '%s'", addedTask.Name))
+		}
+
+		feature, err := geojson.UnmarshalFeature([]byte(addedTask.Geometry))
+		if err != nil {
+			return errors.Wrapf(err, "Geometry of task is no valid feature: %s", addedTask.Geometry)
+		}
+		if !feature.Geometry.IsMultiPolygon() || len(feature.Geometry.MultiPolygon) != 2 {
+			return errors.New(fmt.Sprintf("Geometry should be multi-polygon with two polygons: %s", addedTask.Geometry))
+		}
+		if feature.Properties["color"] != "red" {
+			return errors.New(fmt.Sprintf("Properties should be kept: %s", addedTask.Geometry))
+		}
+
+		return nil
+	})
+}
+
 func TestAddTasksInvalidProcessPoints(t *testing.T) {
 	h.Run(t, func() error {
 		// Max points = 0 is not allowed
 		rawTask := DraftDto{
 			MaxProcessPoints: 0,
-			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[1,0]]]},\"properties\":null}",
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[0.01,0],[0.01,0.01],[0,0]]]},\"properties\":null}",
 		}
 
 		_, err := s.AddTasks([]DraftDto{rawTask}, "1")
@@ -139,6 +395,71 @@ func TestAddTasksInvalidProcessPoints(t *testing.T) {
 	})
 }
 
+func TestAddTasksRepairsGeometry(t *testing.T) {
+	h.Run(t, func() error {
+		// Clockwise and not closed
+		rawTask := DraftDto{
+			MaxProcessPoints: 10,
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[0,0.01],[0.01,0.01]]]},\"properties\":{\"name\":\"foo\"}}",
+		}
+
+		addedTasks, err := s.AddTasks([]DraftDto{rawTask}, "1")
+		if err != nil {
+			return err
+		}
+
+		addedTask := addedTasks[0]
+		if !strings.Contains(addedTask.Geometry, "[[[0,0],[0.01,0.01],[0,0.01],[0,0]]]") {
+			return errors.New(fmt.Sprintf("Geometry should be repaired: %s", addedTask.Geometry))
+		}
+		if addedTask.Name != "foo" {
+			return errors.New("Properties should be kept when repairing the geometry")
+		}
+
+		return nil
+	})
+}
+
+func TestAddTasksReportsAllInvalidDrafts(t *testing.T) {
+	h.Run(t, func() error {
+		validTask := DraftDto{
+			MaxProcessPoints: 10,
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[0.01,0],[0.01,0.01],[0,0]]]},\"properties\":null}",
+		}
+		selfIntersectingTask := DraftDto{
+			MaxProcessPoints: 10,
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[0.01,0.01],[0.01,0],[0,0.01],[0,0]]]},\"properties\":null}",
+		}
+		oversizedTask := DraftDto{
+			MaxProcessPoints: 10,
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[10,0],[10,10],[0,0]]]},\"properties\":null}",
+		}
+
+		_, err := s.AddTasks([]DraftDto{validTask, selfIntersectingTask, oversizedTask}, "1")
+		if err == nil {
+			return errors.New("Adding invalid tasks should not work")
+		}
+
+		draftError, ok := err.(*DraftError)
+		if !ok {
+			return errors.New(fmt.Sprintf("Error should be a DraftError but was %#v", err))
+		}
+		if len(draftError.Problems) != 2 ||
+			!strings.HasPrefix(draftError.Problems[0], "task 1:") ||
+			!strings.HasPrefix(draftError.Problems[1], "task 2:") {
+			return errors.New(fmt.Sprintf("Problems of both invalid tasks expected: %v", draftError.Problems))
+		}
+
+		config.Conf.MinTaskArea = 1000000000
+		_, err = s.AddTasks([]DraftDto{validTask}, "1")
+		if err == nil {
+			return errors.New("Adding too small tasks should not work")
+		}
+
+		return nil
+	})
+}
+
 func TestAddTasksInvalidGeometry(t *testing.T) {
 	h.Run(t, func() error {
 		t := DraftDto{
@@ -187,17 +508,17 @@ func TestAddTasksInvalidGeometry(t *testing.T) {
 
 func TestAssignUser(t *testing.T) {
 	h.Run(t, func() error {
-		task, err := s.AssignUser("2", "assigned-user")
+		task, err := s.AssignUser("2", "John")
 		if err != nil {
 			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
 		}
 
-		if task.AssignedUser != "assigned-user" {
+		if task.AssignedUser != "John" {
 			return errors.New(fmt.Sprintf("Assigned user on task does not match\n"))
 		}
 
 		// not existing task should cause error
-		_, err = s.AssignUser("300", "assigned-user")
+		_, err = s.AssignUser("300", "John")
 		if err == nil { // database returns just not a task
 			return errors.New(fmt.Sprintf("Should be unable to assign user to not existing task\n"))
 		}
@@ -213,12 +534,12 @@ func TestAssignUser(t *testing.T) {
 
 func TestAssignUserTwice(t *testing.T) {
 	h.Run(t, func() error {
-		_, err := s.AssignUser("4", "foo-bar")
+		_, err := s.AssignUser("4", "Carl")
 		if err != nil {
 			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
 		}
 
-		_, err = s.AssignUser("4", "foo-bar")
+		_, err = s.AssignUser("4", "Carl")
 		if err == nil {
 			return errors.New(fmt.Sprintf("Should not be able to overwrite assigned user"))
 		}
@@ -228,9 +549,9 @@ func TestAssignUserTwice(t *testing.T) {
 
 func TestUnassignUser(t *testing.T) {
 	h.Run(t, func() error {
-		s.AssignUser("2", "assigned-user")
+		s.AssignUser("2", "John")
 
-		task, err := s.UnassignUser("2", "assigned-user")
+		task, err := s.UnassignUser("2", "John")
 		if err != nil {
 			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
 		}
@@ -240,7 +561,7 @@ func TestUnassignUser(t *testing.T) {
 		}
 
 		// not existing task should cause error
-		_, err = s.UnassignUser("300", "assigned-user")
+		_, err = s.UnassignUser("300", "John")
 		if err == nil { // database returns just not a task
 			return errors.New(fmt.Sprintf("Should be unable to unassign user from not existing task\n"))
 		}
@@ -254,72 +575,701 @@ func TestUnassignUser(t *testing.T) {
 	})
 }
 
-func TestSetProcessPoints(t *testing.T) {
+func TestUnassignUserByOwner(t *testing.T) {
 	h.Run(t, func() error {
-		// Test Increase number
-		task, err := s.SetProcessPoints("3", 70, "Maria")
+		// Task 7 is assigned to Donny, Maria is the owner of project 2
+		task, err := s.UnassignUser("7", "Maria")
 		if err != nil {
-			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
+			return errors.New(fmt.Sprintf("Owner should be able to unassign other users: %s", err.Error()))
+		}
+		if task.AssignedUser != "" {
+			return errors.New(fmt.Sprintf("Assigned user on task not empty: %s", task.AssignedUser))
 		}
 
-		if task.ProcessPoints != 70 {
-			return errors.New(fmt.Sprintf("Increase should work\n"))
+		events, err := s.GetHistory("7", "Maria")
+		if err != nil {
+			return err
 		}
 
-		// Test Decrease number
-		task, err = s.SetProcessPoints("3", 10, "Maria")
+		lastEvent := events[len(events)-1]
+		if lastEvent.Type != EventUnassignment || lastEvent.UserId != "Maria" || lastEvent.AssignedUser != "Donny" {
+			return errors.New(fmt.Sprintf("Unassignment should be recorded as done by Maria for Donny: %#v", lastEvent))
+		}
+
+		return nil
+	})
+}
+
+func TestUnassignExpiredTasks(t *testing.T) {
+	h.Run(t, func() error {
+		// Fresh assignment in the same project should not expire
+		_, err := s.AssignUser("5", "Otto")
 		if err != nil {
 			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
 		}
 
-		if task.ProcessPoints != 10 {
-			return errors.New(fmt.Sprintf("Decrease should work\n"))
+		tasks, err := s.UnassignExpiredTasks()
+		if err != nil {
+			return errors.New(fmt.Sprintf("Unassigning expired tasks should work: %s", err.Error()))
 		}
 
-		// Test negative number
-		task, err = s.SetProcessPoints("3", -10, "Maria")
-		if err == nil {
-			return errors.New(fmt.Sprintf("Negative numbers not allowed\n"))
+		if len(tasks) != 1 || tasks[0].Id != "8" {
+			return errors.New(fmt.Sprintf("Only task 8 should be unassigned but got %v", tasks))
 		}
 
-		// Test not assigned user
-		task, err = s.SetProcessPoints("3", 20, "Max")
-		if err == nil {
-			return errors.New(fmt.Sprintf("Only assigned user is allowed to set process points\n"))
+		if tasks[0].AssignedUser != "" {
+			return errors.New("Task 8 should not have an assigned user anymore")
 		}
 
-		// Test not existing project
-		task, err = s.SetProcessPoints("300", 20, "Max")
-		if err == nil { // database returns just not a task
-			return errors.New(fmt.Sprintf("Should be unable to set points on not existing task\n"))
+		if len(tasks[0].Comments) != 1 || tasks[0].Comments[0].AuthorId != comment.SystemAuthorId {
+			return errors.New(fmt.Sprintf("Task 8 should have a system comment but has %v", tasks[0].Comments))
 		}
 
-		// Task where no assignment is needed
-		_, err = s.SetProcessPoints("5", 20, "Otto")
+		task, err := s.GetTask("5")
 		if err != nil {
-			return errors.New(fmt.Sprintf("Should be able to set process points without assignment: %s", err.Error()))
+			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
+		}
+		if task.AssignedUser != "Otto" {
+			return errors.New("Task 5 should still be assigned")
+		}
+
+		// Nothing left to unassign
+		tasks, err = s.UnassignExpiredTasks()
+		if err != nil || len(tasks) != 0 {
+			return errors.New(fmt.Sprintf("No more tasks should be unassigned: %v", tasks))
 		}
+
 		return nil
 	})
 }
 
-func TestDelete(t *testing.T) {
+func TestValidationWorkflow(t *testing.T) {
 	h.Run(t, func() error {
-		// tasks of project 2
-		taskIds := []string{"6", "7"}
+		_, err := tx.Exec("UPDATE projects SET needs_validation=true WHERE id=2")
+		if err != nil {
+			return err
+		}
 
-		err := s.Delete(taskIds, "Maria")
+		_, err = s.AssignUser("6", "John")
 		if err != nil {
-			return errors.New(fmt.Sprintf("error deleting tasks: %s", err.Error()))
+			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
 		}
 
-		remainingTasks, err := s.store.GetAllTasksOfProject("2")
+		task, err := s.SetProcessPoints("6", 4, "John")
 		if err != nil {
-			return errors.New("Getting remaining tasks should work")
+			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
+		}
+		if task.ValidationState != NeedsReview || task.Mapper != "John" {
+			return errors.New(fmt.Sprintf("Finished task should need review: %s by %s", task.ValidationState, task.Mapper))
 		}
 
-		if len(remainingTasks) != 3 {
-			return errors.New(fmt.Sprintf("Expect 3 remaining tasks but found %d", len(remainingTasks)))
+		// Mappers can't validate their own work
+		var permissionError *permission.PermissionError
+		_, err = s.ValidateTask("6", "John")
+		if !errors.As(err, &permissionError) {
+			return errors.New(fmt.Sprintf("Mapper should not be allowed to validate own task: %v", err))
+		}
+
+		// Rejection needs an explanation
+		var requestError *util.InvalidRequestError
+		_, err = s.RejectTask("6", &comment.DraftDto{Text: " "}, "Anna")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Rejection without comment should be an invalid request: %v", err))
+		}
+
+		task, err = s.RejectTask("6", &comment.DraftDto{Text: "Buildings are missing"}, "Anna")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Rejecting task should work: %s", err.Error()))
+		}
+		if task.ValidationState != Mapping || task.ProcessPoints != 0 || task.Mapper != "" {
+			return errors.New(fmt.Sprintf("Rejected task should be mapped again: %v", task))
+		}
+		if len(task.Comments) != 1 || task.Comments[0].AuthorId != "Anna" {
+			return errors.New(fmt.Sprintf("Rejection comment not added: %v", task.Comments))
+		}
+
+		_, err = s.SetProcessPoints("6", 4, "John")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
+		}
+
+		// Non-members can't validate
+		_, err = s.ValidateTask("6", "Peter")
+		if err == nil {
+			return errors.New("Non-member should not be able to validate task")
+		}
+
+		task, err = s.ValidateTask("6", "Anna")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Validating task should work: %s", err.Error()))
+		}
+		if task.ValidationState != Validated || task.Validator != "Anna" || task.Mapper != "John" {
+			return errors.New(fmt.Sprintf("Task should be validated: %v", task))
+		}
+
+		// Validated tasks can't be changed
+		_, err = s.SetProcessPoints("6", 2, "John")
+		if err == nil {
+			return errors.New("Process points of validated task should not be changeable")
+		}
+
+		// Project without validation
+		_, err = s.ValidateTask("5", "Otto")
+		if err == nil {
+			return errors.New("Task of project without validation should not be validatable")
+		}
+
+		return nil
+	})
+}
+
+func TestGetHistory(t *testing.T) {
+	h.Run(t, func() error {
+		_, err := s.SetProcessPoints("3", 70, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
+		}
+
+		_, err = s.UnassignUser("3", "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
+		}
+
+		_, err = s.AssignUser("3", "John")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
+		}
+
+		events, err := s.GetHistory("3", "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error getting history: %s\n", err.Error()))
+		}
+
+		if len(events) != 3 {
+			return errors.New(fmt.Sprintf("Expected 3 events but got %d", len(events)))
+		}
+
+		if events[0].Type != EventProcessPoints || events[0].UserId != "Maria" || events[0].PreviousProcessPoints != 50 || events[0].ProcessPoints != 70 {
+			return errors.New(fmt.Sprintf("Process point event not correct: %v", events[0]))
+		}
+
+		if events[1].Type != EventUnassignment || events[1].AssignedUser != "Maria" {
+			return errors.New(fmt.Sprintf("Unassignment event not correct: %v", events[1]))
+		}
+
+		if events[2].Type != EventAssignment || events[2].AssignedUser != "John" || events[2].CreationDate == nil {
+			return errors.New(fmt.Sprintf("Assignment event not correct: %v", events[2]))
+		}
+
+		// Non-members should not see the history
+		_, err = s.GetHistory("3", "Otto")
+		if err == nil {
+			return errors.New("Non-member should not be able to get history")
+		}
+
+		return nil
+	})
+}
+
+func TestSetProcessPoints(t *testing.T) {
+	h.Run(t, func() error {
+		// Test Increase number
+		task, err := s.SetProcessPoints("3", 70, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
+		}
+
+		if task.ProcessPoints != 70 {
+			return errors.New(fmt.Sprintf("Increase should work\n"))
+		}
+
+		// Test Decrease number
+		task, err = s.SetProcessPoints("3", 10, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error: %s\n", err.Error()))
+		}
+
+		if task.ProcessPoints != 10 {
+			return errors.New(fmt.Sprintf("Decrease should work\n"))
+		}
+
+		// Test negative number
+		task, err = s.SetProcessPoints("3", -10, "Maria")
+		if err == nil {
+			return errors.New(fmt.Sprintf("Negative numbers not allowed\n"))
+		}
+
+		// Test not assigned user
+		task, err = s.SetProcessPoints("3", 20, "Max")
+		if err == nil {
+			return errors.New(fmt.Sprintf("Only assigned user is allowed to set process points\n"))
+		}
+
+		// Test not existing project
+		task, err = s.SetProcessPoints("300", 20, "Max")
+		if err == nil { // database returns just not a task
+			return errors.New(fmt.Sprintf("Should be unable to set points on not existing task\n"))
+		}
+
+		// Task where no assignment is needed
+		_, err = s.SetProcessPoints("5", 20, "Otto")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Should be able to set process points without assignment: %s", err.Error()))
+		}
+		return nil
+	})
+}
+
+func TestDelete(t *testing.T) {
+	h.Run(t, func() error {
+		// tasks of project 2
+		taskIds := []string{"6", "7"}
+
+		err := s.AddComment("6", &comment.DraftDto{Text: "Some comment"}, "Maria")
+		if err != nil {
+			return err
+		}
+
+		// Moderators are allowed to delete tasks
+		_, err = tx.Exec("UPDATE project_members SET role='MODERATOR' WHERE project_id=2 AND user_id='John'")
+		if err != nil {
+			return err
+		}
+
+		err = s.Delete(taskIds, "John")
+		if err != nil {
+			return errors.New(fmt.Sprintf("error deleting tasks: %s", err.Error()))
+		}
+
+		remainingTasks, err := s.store.GetAllTasksOfProject("2")
+		if err != nil {
+			return errors.New("Getting remaining tasks should work")
+		}
+
+		if len(remainingTasks) != 3 {
+			return errors.New(fmt.Sprintf("Expect 3 remaining tasks but found %d", len(remainingTasks)))
+		}
+
+		// Comment lists 7 and 8 belong to the deleted tasks
+		var numberOfCommentLists, numberOfComments int
+		err = tx.QueryRow("SELECT (SELECT COUNT(*) FROM comment_lists WHERE id IN (7, 8)), (SELECT COUNT(*) FROM comments WHERE comment_list_id IN (7, 8))").Scan(&numberOfCommentLists, &numberOfComments)
+		if err != nil {
+			return err
+		}
+		if numberOfCommentLists != 0 || numberOfComments != 0 {
+			return errors.New(fmt.Sprintf("Comments of deleted tasks should be deleted but found %d lists and %d comments", numberOfCommentLists, numberOfComments))
+		}
+
+		return nil
+	})
+}
+
+func TestDeleteWithInvalidParameters(t *testing.T) {
+	h.Run(t, func() error {
+		var permissionError *permission.PermissionError
+		err := s.Delete([]string{"6"}, "John")
+		if !errors.As(err, &permissionError) {
+			return errors.New(fmt.Sprintf("Only the owner or a moderator should be able to delete tasks: %v", err))
+		}
+
+		// Task 1 belongs to project 1, task 6 to project 2
+		err = s.Delete([]string{"1", "6"}, "Maria")
+		if err == nil {
+			return errors.New("Deleting tasks of different projects should not work")
+		}
+
+		var requestError *util.InvalidRequestError
+		err = s.Delete([]string{"1"}, "Peter")
+		if !errors.As(err, &requestError) {
+			return errors.New(fmt.Sprintf("Deleting the last task of a project should be an invalid request: %v", err))
+		}
+
+		return nil
+	})
+}
+
+func TestUpdateTask(t *testing.T) {
+	h.Run(t, func() error {
+		dto := &UpdateDto{
+			Name:             "New name",
+			MaxProcessPoints: 30,
+		}
+
+		_, err := s.UpdateTask("3", dto, "John")
+		if err == nil {
+			return errors.New("Only the owner or a moderator should be able to update tasks")
+		}
+
+		_, err = s.UpdateTask("3", &UpdateDto{MaxProcessPoints: 0}, "Maria")
+		if err == nil {
+			return errors.New("Maximum process points of 0 should not be allowed")
+		}
+
+		_, err = s.UpdateTask("3", &UpdateDto{MaxProcessPoints: 10, Geometry: "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Point\",\"coordinates\":[0,0]},\"properties\":null}"}, "Maria")
+		if err == nil {
+			return errors.New("Invalid geometry should not be allowed")
+		}
+
+		// Task 3 has 50 of 100 process points
+		task, err := s.UpdateTask("3", dto, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Updating task should work: %s", err.Error()))
+		}
+
+		if task.Name != "New name" {
+			return errors.New(fmt.Sprintf("Name should be updated but was '%s'", task.Name))
+		}
+		if task.MaxProcessPoints != 30 || task.ProcessPoints != 30 {
+			return errors.New(fmt.Sprintf("Process points should be clamped to 30/30 but were %d/%d", task.ProcessPoints, task.MaxProcessPoints))
+		}
+
+		events, err := s.store.getEvents("3")
+		if err != nil {
+			return err
+		}
+		lastEvent := events[len(events)-1]
+		if lastEvent.Type != EventMaxPoints || lastEvent.PreviousProcessPoints != 50 || lastEvent.ProcessPoints != 30 {
+			return errors.New(fmt.Sprintf("Lowering process points should be recorded: %#v", lastEvent))
+		}
+
+		newGeometry := "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[0.01,0],[0.01,0.01],[0,0]]]},\"properties\":{\"name\":\"foo\"}}"
+		task, err = s.UpdateTask("3", &UpdateDto{MaxProcessPoints: 100, Geometry: newGeometry}, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Updating geometry should work: %s", err.Error()))
+		}
+
+		if task.Name != "" || task.ProcessPoints != 30 || task.MaxProcessPoints != 100 {
+			return errors.New(fmt.Sprintf("Task not updated correctly: %#v", task))
+		}
+		if !strings.Contains(task.Geometry, "[[[0,0],[0.01,0],[0.01,0.01],[0,0]]]") {
+			return errors.New(fmt.Sprintf("Geometry not replaced: %s", task.Geometry))
+		}
+
+		return nil
+	})
+}
+
+func TestUpdateTaskNeedsReview(t *testing.T) {
+	h.Run(t, func() error {
+		_, err := tx.Exec("UPDATE projects SET needs_validation=true WHERE id=2")
+		if err != nil {
+			return err
+		}
+
+		_, err = s.SetProcessPoints("3", 60, "Maria")
+		if err != nil {
+			return err
+		}
+
+		// Lowering the maximum process points finishes the task, so it needs to be reviewed like any other finished task
+		task, err := s.UpdateTask("3", &UpdateDto{MaxProcessPoints: 40}, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Updating task should work: %s", err.Error()))
+		}
+		if task.ProcessPoints != 40 || task.ValidationState != NeedsReview || task.Mapper != "Maria" {
+			return errors.New(fmt.Sprintf("Task should need review with Maria as mapper: %#v", task))
+		}
+
+		// Raising the maximum again sends the task back to mapping
+		task, err = s.UpdateTask("3", &UpdateDto{MaxProcessPoints: 100}, "Maria")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Updating task should work: %s", err.Error()))
+		}
+		if task.ProcessPoints != 40 || task.ValidationState != Mapping {
+			return errors.New(fmt.Sprintf("Task should be back in mapping: %#v", task))
+		}
+
+		return nil
+	})
+}
+
+func TestCheckCoverage(t *testing.T) {
+	h.Run(t, func() error {
+		_, err := s.CheckCoverage("2", nil, "Peter")
+		if err == nil {
+			return errors.New("Non-member should not be able to check the coverage")
+		}
+
+		// Tasks 4, 6 and 7 have the same geometry
+		coverage, err := s.CheckCoverage("2", nil, "John")
+		if err != nil {
+			return err
+		}
+
+		overlapBetween4And6 := false
+		numberOfGaps := 0
+		for _, feature := range coverage.Features {
+			problemType, _ := feature.PropertyString("type")
+			if problemType == "gap" {
+				numberOfGaps++
+				continue
+			}
+
+			taskIds, ok := feature.Properties["taskIds"].([]string)
+			if ok && taskIds[0] == "4" && taskIds[1] == "6" {
+				overlapBetween4And6 = true
+			}
+		}
+		if !overlapBetween4And6 {
+			return errors.New(fmt.Sprintf("Overlap between task 4 and 6 expected: %#v", coverage.Features))
+		}
+
+		// The tiny task 2 is far away from the other tasks, so there's a huge gap within the convex hull
+		if numberOfGaps == 0 {
+			return errors.New("Gaps within the convex hull of all tasks expected")
+		}
+
+		task4, err := s.GetTask("4")
+		if err != nil {
+			return err
+		}
+		boundary, err := geojson.UnmarshalFeature([]byte(task4.Geometry))
+		if err != nil {
+			return err
+		}
+
+		coverage, err = s.CheckCoverage("2", boundary.Geometry, "John")
+		if err != nil {
+			return err
+		}
+		for _, feature := range coverage.Features {
+			problemType, _ := feature.PropertyString("type")
+			if problemType == "gap" {
+				return errors.New(fmt.Sprintf("Boundary is completely covered by task 4 but found gap %#v", feature.Geometry))
+			}
+		}
+
+		return nil
+	})
+}
+
+func TestSplitArea(t *testing.T) {
+	h.Run(t, func() error {
+		splitDto := &SplitDto{
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[9.98,53.55],[9.995,53.55],[9.995,53.559],[9.98,53.559],[9.98,53.55]]]},\"properties\":null}",
+			Mode:             "squareGrid",
+			CellSize:         500,
+			MaxProcessPoints: 10,
+		}
+
+		drafts, err := s.SplitArea(splitDto)
+		if err != nil {
+			return err
+		}
+
+		if len(drafts) < 4 {
+			return errors.New(fmt.Sprintf("Expected at least 4 task drafts but got %d", len(drafts)))
+		}
+		for _, draft := range drafts {
+			if draft.MaxProcessPoints != 10 || draft.ProcessPoints != 0 {
+				return errors.New(fmt.Sprintf("Process points of draft not matching: %#v", draft))
+			}
+		}
+
+		// Drafts must be valid input to create tasks
+		_, err = s.AddTasks(drafts, "1")
+		if err != nil {
+			return errors.Wrap(err, "Adding split task drafts should work")
+		}
+
+		return nil
+	})
+}
+
+func TestSplitAreaTooManyTasks(t *testing.T) {
+	h.Run(t, func() error {
+		splitDto := &SplitDto{
+			Geometry:         "{\"type\":\"Feature\",\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[9.98,53.55],[9.995,53.55],[9.995,53.559],[9.98,53.559],[9.98,53.55]]]},\"properties\":null}",
+			Mode:             "hexGrid",
+			CellSize:         1,
+			MaxProcessPoints: 10,
+		}
+
+		_, err := s.SplitArea(splitDto)
+		if err == nil {
+			return errors.New("Splitting into way too many tasks should not work")
+		}
+
+		// Rejected before creating any cell
+		splitDto.Mode = "voronoi"
+		splitDto.CellCount = config.Conf.MaxTasksPerProject + 1
+		_, err = s.SplitArea(splitDto)
+		if err == nil {
+			return errors.New("Splitting into more voronoi cells than tasks allowed should not work")
+		}
+		splitDto.Mode = "hexGrid"
+
+		// Not a polygon
+		splitDto.Geometry = "{\"type\":\"Feature\",\"geometry\":{\"type\":\"LineString\",\"coordinates\":[[0,0],[1,0]]},\"properties\":null}"
+		splitDto.CellSize = 100
+		_, err = s.SplitArea(splitDto)
+		if err == nil {
+			return errors.New("Splitting a line should not work")
+		}
+
+		return nil
+	})
+}
+
+func TestSplitTask(t *testing.T) {
+	h.Run(t, func() error {
+		splitDto := &TaskSplitDto{
+			Mode:      "voronoi",
+			CellCount: 3,
+		}
+
+		// Maria is not the owner of project 1
+		_, err := s.SplitTask("1", splitDto, "Maria")
+		if err == nil {
+			return errors.New("Non-owner should not be able to split a task")
+		}
+
+		newTasks, err := s.SplitTask("1", splitDto, "Peter")
+		if err != nil {
+			return err
+		}
+
+		if len(newTasks) != 3 {
+			return errors.New(fmt.Sprintf("Expected 3 new tasks but got %d", len(newTasks)))
+		}
+		for _, newTask := range newTasks {
+			if newTask.MaxProcessPoints != 10 || newTask.ProcessPoints != 0 || newTask.AssignedUser != "" {
+				return errors.New(fmt.Sprintf("New task does not match: %#v", newTask))
+			}
+			if len(newTask.Comments) != 2 {
+				return errors.New(fmt.Sprintf("Expected comments of original task on new task but got %#v", newTask.Comments))
+			}
+		}
+
+		_, err = s.GetTask("1")
+		if err == nil {
+			return errors.New("Original task should not exist anymore")
+		}
+
+		return nil
+	})
+}
+
+func TestSplitTaskKeepsValidationStateAndHistory(t *testing.T) {
+	h.Run(t, func() error {
+		_, err := s.store.setValidationState("1", Validated, "Peter", "Maria")
+		if err != nil {
+			return err
+		}
+
+		newTasks, err := s.SplitTask("1", &TaskSplitDto{Mode: "voronoi", CellCount: 2}, "Peter")
+		if err != nil {
+			return err
+		}
+
+		for _, newTask := range newTasks {
+			if newTask.ValidationState != Validated || newTask.Mapper != "Peter" || newTask.Validator != "Maria" {
+				return errors.New(fmt.Sprintf("Validation state of new task does not match: %#v", newTask))
+			}
+
+			events, err := s.store.getEvents(newTask.Id)
+			if err != nil {
+				return err
+			}
+			if len(events) != 1 || events[0].Type != EventSplit || events[0].UserId != "Peter" {
+				return errors.New(fmt.Sprintf("Expected split event on new task but got %#v", events))
+			}
+		}
+
+		// The history of the original task survives
+		events, err := s.store.getEvents("1")
+		if err != nil {
+			return err
+		}
+		if len(events) != 1 || events[0].Type != EventSplit {
+			return errors.New(fmt.Sprintf("Expected split event on original task but got %#v", events))
+		}
+
+		return nil
+	})
+}
+
+func TestMergeTasks(t *testing.T) {
+	h.Run(t, func() error {
+		// Maria is the owner of project 2
+		mergedTask, err := s.MergeTasks([]string{"2", "4"}, "Maria")
+		if err != nil {
+			return err
+		}
+
+		if mergedTask.MaxProcessPoints != 200 || mergedTask.ProcessPoints != 100 || mergedTask.AssignedUser != "" {
+			return errors.New(fmt.Sprintf("Merged task does not match: %#v", mergedTask))
+		}
+
+		remainingTasks, err := s.store.GetAllTasksOfProject("2")
+		if err != nil {
+			return err
+		}
+		if len(remainingTasks) != 4 {
+			return errors.New(fmt.Sprintf("Expect 4 remaining tasks but found %d", len(remainingTasks)))
+		}
+
+		return nil
+	})
+}
+
+func TestMergeTasksKeepsAssignment(t *testing.T) {
+	h.Run(t, func() error {
+		// Otto is the owner of project 3 and is assigned to task 8
+		mergedTask, err := s.MergeTasks([]string{"5", "8"}, "Otto")
+		if err != nil {
+			return err
+		}
+
+		if mergedTask.AssignedUser != "Otto" || mergedTask.ProcessPoints != 345 {
+			return errors.New(fmt.Sprintf("Merged task does not match: %#v", mergedTask))
+		}
+
+		// The assignment of the merged task expires like every other assignment
+		expiredTaskIds, err := s.store.getTaskIdsWithExpiredAssignment(time.Now().UTC().Add(25 * time.Hour))
+		if err != nil {
+			return err
+		}
+		if len(expiredTaskIds) != 1 || expiredTaskIds[0] != mergedTask.Id {
+			return errors.New(fmt.Sprintf("Expected expired assignment of merged task but got %v", expiredTaskIds))
+		}
+
+		events, err := s.store.getEvents(mergedTask.Id)
+		if err != nil {
+			return err
+		}
+		if len(events) != 1 || events[0].Type != EventMerge || events[0].AssignedUser != "Otto" || events[0].ProcessPoints != 345 {
+			return errors.New(fmt.Sprintf("Expected merge event on merged task but got %#v", events))
+		}
+
+		return nil
+	})
+}
+
+func TestMergeTasksNotAllowed(t *testing.T) {
+	h.Run(t, func() error {
+		// Not the owner
+		_, err := s.MergeTasks([]string{"2", "4"}, "John")
+		if err == nil {
+			return errors.New("Non-owner should not be able to merge tasks")
+		}
+
+		// Different projects
+		_, err = s.MergeTasks([]string{"1", "2"}, "Maria")
+		if err == nil {
+			return errors.New("Merging tasks of different projects should not work")
+		}
+
+		// Assigned to different users
+		_, err = s.MergeTasks([]string{"3", "7"}, "Maria")
+		if err == nil {
+			return errors.New("Merging tasks assigned to different users should not work")
+		}
+
+		// Only one task
+		_, err = s.MergeTasks([]string{"2", "2"}, "Maria")
+		if err == nil {
+			return errors.New("Merging one task with itself should not work")
 		}
 
 		return nil
@@ -4,11 +4,12 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/lib/pq"
-	geojson "github.com/paulmach/go.geojson"
 	"github.com/pkg/errors"
 	"stm/comment"
 	"stm/util"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type taskRow struct {
@@ -16,19 +17,55 @@ type taskRow struct {
 	processPoints    int
 	maxProcessPoints int
 	geometry         string
+	name             string
 	assignedUser     string
 	commentListId    string
+	validationState  string
+	mapper           string
+	validator        string
+	projectId        string
+}
+
+type eventRow struct {
+	id                    int
+	taskId                int
+	eventType             string
+	userId                string
+	assignedUser          string
+	previousProcessPoints int
+	processPoints         int
+	creationDate          *time.Time
 }
 
 type Store struct {
 	*util.Logger
 	tx           *sql.Tx
 	Table        string
+	eventTable   string
 	commentStore *comment.Store
 }
 
 var (
-	returnValues = "id, process_points, max_process_points, geometry, assigned_user, comment_list_id"
+	// Event types changing the process points of a task
+	processPointEventTypes = []string{string(EventProcessPoints), string(EventRejection), string(EventMaxPoints)}
+
+	// Event types resetting the process points another user has set. The user who set them is credited with the change.
+	resetEventTypes = []string{string(EventRejection), string(EventMaxPoints)}
+
+	// Event types of tasks being replaced by other tasks. They change the process points of the project but are no
+	// contributions of the user performing them.
+	replacementEventTypes = []string{string(EventSplit), string(EventMerge)}
+
+	// Event types changing the process points of the project
+	progressEventTypes = append(append([]string{}, processPointEventTypes...), replacementEventTypes...)
+
+	// The geometry (a PostGIS multi-polygon) and the JSONB properties are turned into a GeoJSON feature again. A
+	// multi-polygon consisting of only one polygon becomes a simple polygon.
+	featureValue = `'{"type":"Feature","geometry":' ||
+		ST_AsGeoJSON(CASE WHEN ST_NumGeometries(geometry) = 1 THEN ST_GeometryN(geometry, 1) ELSE geometry END, 15) ||
+		',"properties":' || COALESCE(properties::text, 'null') || '}'`
+
+	returnValues = "id, process_points, max_process_points, " + featureValue + ", COALESCE(properties->>'name', ''), assigned_user, comment_list_id, validation_state, mapper, validator, project_id"
 )
 
 func GetStore(tx *sql.Tx, logger *util.Logger, commentStore *comment.Store) *Store {
@@ -36,17 +73,163 @@ func GetStore(tx *sql.Tx, logger *util.Logger, commentStore *comment.Store) *Sto
 		Logger:       logger,
 		tx:           tx,
 		Table:        "tasks",
+		eventTable:   "task_events",
 		commentStore: commentStore,
 	}
 }
 
 func (s *Store) GetAllTasksOfProject(projectId string) ([]*Task, error) {
-	query := fmt.Sprintf("SELECT id,process_points,max_process_points,geometry,assigned_user,comment_list_id FROM %s WHERE project_id = $1;", s.Table)
-	s.LogQuery(query, projectId)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE project_id = $1 ORDER BY id;", returnValues, s.Table)
 
-	rows, err := s.tx.Query(query, projectId)
+	tasks, err := s.queryTasks(query, projectId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting tasks for project %s", projectId)
+	}
+
+	if len(tasks) == 0 {
+		return nil, errors.New("Tasks do not exist")
+	}
+
+	return tasks, nil
+}
+
+// GetAllTasksOfProjects loads the tasks of all given projects with a constant number of queries. The result maps each
+// project ID to the tasks of that project ordered by their ID. Projects without tasks are not part of the result.
+func (s *Store) GetAllTasksOfProjects(projectIds []string) (map[string][]*Task, error) {
+	result := make(map[string][]*Task, len(projectIds))
+	if len(projectIds) == 0 {
+		return result, nil
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE project_id = ANY($1) ORDER BY id;", returnValues, s.Table)
+
+	tasks, taskRows, err := s.queryTaskRows(query, pq.Array(projectIds))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting tasks for projects %v", projectIds)
+	}
+
+	for i, task := range tasks {
+		projectId := taskRows[i].projectId
+		result[projectId] = append(result[projectId], task)
+	}
+
+	return result, nil
+}
+
+// getTasksPage returns at most "limit" tasks of the project, which match the filters of the list DTO. The tasks are
+// sorted as requested and start after the task of the cursor (if given).
+func (s *Store) getTasksPage(projectId string, listDto *ListDto, cursor *pageCursor, limit int) ([]*Task, error) {
+	params := []interface{}{projectId}
+	param := func(value interface{}) string {
+		params = append(params, value)
+		return fmt.Sprintf("$%d", len(params))
+	}
+
+	conditions := []string{"project_id = $1"}
+	if listDto.AssignedUser != "" {
+		conditions = append(conditions, "assigned_user = "+param(listDto.AssignedUser))
+	}
+	if listDto.Unassigned {
+		conditions = append(conditions, "assigned_user = ''")
+	}
+	switch listDto.State {
+	case StateNotStarted:
+		conditions = append(conditions, "process_points = 0")
+	case StateInProgress:
+		conditions = append(conditions, "process_points > 0 AND process_points < max_process_points")
+	case StateDone:
+		conditions = append(conditions, "process_points = max_process_points")
+	}
+	if listDto.HasComments {
+		conditions = append(conditions, fmt.Sprintf("EXISTS(SELECT 1 FROM comments c WHERE c.comment_list_id = %s.comment_list_id)", s.Table))
+	}
+	if listDto.BoundingBox != nil {
+		bbox := listDto.BoundingBox
+		conditions = append(conditions, fmt.Sprintf("ST_Intersects(geometry, ST_MakeEnvelope(%s, %s, %s, %s, 4326))", param(bbox.MinLon), param(bbox.MinLat), param(bbox.MaxLon), param(bbox.MaxLat)))
+	}
+
+	// Sort expression and its type for the comparison with the cursor value
+	sortExpression, sortType := "", ""
+	switch listDto.Sort {
+	case SortByName:
+		sortExpression, sortType = "COALESCE(properties->>'name', '')", "text"
+	case SortByProgress:
+		sortExpression, sortType = "process_points::float8 / max_process_points", "float8"
+	case SortByAssignedUser:
+		sortExpression, sortType = "assigned_user", "text"
+	}
+
+	direction, comparison := "ASC", ">"
+	if listDto.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	order := fmt.Sprintf("id %s", direction)
+	if sortExpression != "" {
+		order = fmt.Sprintf("%s %s, %s", sortExpression, direction, order)
+	}
+
+	if cursor != nil {
+		if sortExpression != "" {
+			conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s::%s, %s)", sortExpression, comparison, param(cursor.Value), sortType, param(cursor.TaskId)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("id %s %s", comparison, param(cursor.TaskId)))
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT %s;", returnValues, s.Table, strings.Join(conditions, " AND "), order, param(limit))
+
+	tasks, err := s.queryTasks(query, params...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting page of tasks for project %s", projectId)
+	}
+
+	return tasks, nil
+}
+
+func (s *Store) getTask(taskId string) (*Task, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1;", returnValues, s.Table)
+	s.LogQuery(query, taskId)
+
+	task, err := s.execQuery(query, taskId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// getTasks returns the tasks with the given IDs ordered by their ID.
+func (s *Store) getTasks(taskIds []string) ([]*Task, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = ANY($1) ORDER BY id;", returnValues, s.Table)
+
+	tasks, err := s.queryTasks(query, pq.Array(taskIds))
 	if err != nil {
-		return nil, errors.Wrapf(err, "error executing query to get tasks for project %s", projectId)
+		return nil, errors.Wrapf(err, "error getting tasks %v", taskIds)
+	}
+
+	if len(tasks) != len(taskIds) {
+		return nil, errors.New(fmt.Sprintf("Only %d of the %d tasks %v exist", len(tasks), len(taskIds), taskIds))
+	}
+
+	return tasks, nil
+}
+
+// queryTasks executes the query and turns all resulting rows into tasks including their comments.
+func (s *Store) queryTasks(query string, params ...interface{}) ([]*Task, error) {
+	tasks, _, err := s.queryTaskRows(query, params...)
+	return tasks, err
+}
+
+// queryTaskRows executes the query and returns the tasks including their comments as well as the raw rows of the
+// tasks. The comments of all tasks are loaded with one single query.
+func (s *Store) queryTaskRows(query string, params ...interface{}) ([]*Task, []*taskRow, error) {
+	s.LogQuery(query, params...)
+
+	rows, err := s.tx.Query(query, params...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error executing query")
 	}
 
 	// Read all tasks from the returned rows of the query
@@ -55,7 +238,7 @@ func (s *Store) GetAllTasksOfProject(projectId string) ([]*Task, error) {
 	for rows.Next() {
 		task, taskRow, err := s.rowToTask(rows)
 		if err != nil {
-			return nil, errors.Wrap(err, "error converting row to task")
+			return nil, nil, errors.Wrap(err, "error converting row to task")
 		}
 
 		tasks = append(tasks, task)
@@ -64,48 +247,119 @@ func (s *Store) GetAllTasksOfProject(projectId string) ([]*Task, error) {
 
 	err = rows.Close()
 	if err != nil {
-		return nil, errors.Wrap(err, "error closing rows")
+		return nil, nil, errors.Wrap(err, "error closing rows")
 	}
 
-	if len(tasks) == 0 {
-		return nil, errors.New("Tasks do not exist")
+	// Load the comments of all tasks at once instead of one query per task
+	commentListIds := make([]string, len(taskRows))
+	for i, taskRow := range taskRows {
+		commentListIds[i] = taskRow.commentListId
+	}
+
+	comments, err := s.commentStore.GetCommentsOfLists(commentListIds)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	for i, task := range tasks {
-		comments, err := s.commentStore.GetComments(taskRows[i].commentListId)
-		if err != nil {
-			return nil, err
-		}
-		task.Comments = comments
+		task.Comments = comments[taskRows[i].commentListId]
 	}
 
-	return tasks, nil
+	return tasks, taskRows, nil
 }
 
-func (s *Store) getTask(taskId string) (*Task, error) {
-	query := fmt.Sprintf("SELECT id,process_points,max_process_points,geometry,assigned_user,comment_list_id FROM %s WHERE id = $1;", s.Table)
-	s.LogQuery(query, taskId)
+// getProjectIdOfTasks returns the ID of the project all the given tasks belong to. An error is returned when a task
+// doesn't exist or when the tasks belong to different projects.
+func (s *Store) getProjectIdOfTasks(taskIds []string) (string, error) {
+	query := fmt.Sprintf("SELECT project_id, COUNT(*) FROM %s WHERE id = ANY($1) GROUP BY project_id;", s.Table)
+	s.LogQuery(query, taskIds)
 
-	task, err := s.execQuery(query, taskId)
+	rows, err := s.tx.Query(query, pq.Array(taskIds))
+	if err != nil {
+		return "", errors.Wrapf(err, "error executing query to get project of tasks %v", taskIds)
+	}
+	defer rows.Close()
 
+	if !rows.Next() {
+		return "", &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("tasks %v do not exist", taskIds))}
+	}
+
+	projectId := ""
+	taskCount := 0
+	err = rows.Scan(&projectId, &taskCount)
 	if err != nil {
-		return nil, err
+		return "", errors.Wrap(err, "could not scan row for project id")
 	}
 
-	return task, nil
+	if rows.Next() {
+		return "", &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("tasks %v belong to different projects", taskIds))}
+	}
+	if taskCount != len(taskIds) {
+		return "", &util.InvalidRequestError{Err: errors.New(fmt.Sprintf("only %d of the %d tasks %v exist", taskCount, len(taskIds), taskIds))}
+	}
+
+	return projectId, nil
 }
 
+func (s *Store) getNumberOfTasks(projectId string) (int, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE project_id = $1;", s.Table)
+	s.LogQuery(query, projectId)
+
+	rows, err := s.tx.Query(query, projectId)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error executing query to get number of tasks of project %s", projectId)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return 0, errors.New("there is no next row or an error happened")
+	}
+
+	numberOfTasks := 0
+	err = rows.Scan(&numberOfTasks)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not scan row for number of tasks")
+	}
+
+	return numberOfTasks, nil
+}
+
+// addTasks adds the tasks and returns them in the same order as the given drafts.
 func (s *Store) addTasks(newTasks []DraftDto, projectId string) ([]*Task, error) {
-	taskIds := make([]string, 0)
+	taskIds, err := s.addTasksWithoutFetching(newTasks, projectId, "")
+	if err != nil {
+		return nil, err
+	}
 
-	commentListId, err := s.commentStore.NewCommentList()
+	tasks, err := s.getTasks(taskIds)
 	if err != nil {
 		return nil, err
 	}
 
+	// The tasks are ordered by their ID, so they're brought back into the order of the IDs returned by the insert
+	tasksById := make(map[string]*Task, len(tasks))
+	for _, t := range tasks {
+		tasksById[t.Id] = t
+	}
+	for i, taskId := range taskIds {
+		tasks[i] = tasksById[taskId]
+	}
+
+	return tasks, nil
+}
+
+// addTasksWithoutFetching adds the tasks and returns their IDs. Each task gets its own comment list.
+func (s *Store) addTasksWithoutFetching(newTasks []DraftDto, projectId string, assignedUser string) ([]string, error) {
+	taskIds := make([]string, 0)
+
 	// TODO Do not add one by one but instead build one large query (otherwise it's really slow)
 	for _, t := range newTasks {
-		id, err := s.addTask(&t, projectId, commentListId)
+		commentListId, err := s.commentStore.NewCommentList()
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := s.addTask(&t, projectId, commentListId, assignedUser)
 		if err != nil {
 			s.Err("error adding task: %s", err.Error())
 			return nil, err
@@ -114,12 +368,21 @@ func (s *Store) addTasks(newTasks []DraftDto, projectId string) ([]*Task, error)
 		taskIds = append(taskIds, id)
 	}
 
-	return s.GetAllTasksOfProject(projectId)
+	return taskIds, nil
 }
 
-func (s *Store) addTask(task *DraftDto, projectId string, commentListId string) (string, error) {
-	query := fmt.Sprintf("INSERT INTO %s(process_points, max_process_points, geometry, assigned_user, project_id, comment_list_id) VALUES($1, $2, $3, $4, $5, $6) RETURNING %s;", s.Table, returnValues)
-	t, err := s.execQuery(query, task.ProcessPoints, task.MaxProcessPoints, task.Geometry, "", projectId, commentListId)
+func (s *Store) addTask(task *DraftDto, projectId string, commentListId string, assignedUser string) (string, error) {
+	// The assignment starts now, otherwise it would never expire
+	var assignmentDate *time.Time
+	if assignedUser != "" {
+		now := time.Now().UTC()
+		assignmentDate = &now
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s(process_points, max_process_points, geometry, properties, assigned_user, assignment_date, project_id, comment_list_id)
+VALUES($1, $2, ST_Multi(ST_GeomFromGeoJSON($3::jsonb->'geometry')), NULLIF($3::jsonb->'properties', 'null'::jsonb), $4, $5, $6, $7)
+RETURNING %s;`, s.Table, returnValues)
+	t, err := s.execQuery(query, task.ProcessPoints, task.MaxProcessPoints, task.Geometry, assignedUser, assignmentDate, projectId, commentListId)
 
 	if err != nil {
 		return "", err
@@ -128,31 +391,364 @@ func (s *Store) addTask(task *DraftDto, projectId string, commentListId string)
 	return t.Id, nil
 }
 
-func (s *Store) assignUser(taskId, userId string) (*Task, error) {
-	query := fmt.Sprintf("UPDATE %s SET assigned_user=$1 WHERE id=$2 RETURNING %s;", s.Table, returnValues)
-	return s.execQuery(query, userId, taskId)
+func (s *Store) assignUser(taskId, userId string, assignmentDate time.Time) (*Task, error) {
+	query := fmt.Sprintf("UPDATE %s SET assigned_user=$1, assignment_date=$3 WHERE id=$2 RETURNING %s;", s.Table, returnValues)
+	return s.execQuery(query, userId, taskId, assignmentDate)
 }
 
 func (s *Store) unassignUser(taskId string) (*Task, error) {
-	query := fmt.Sprintf("UPDATE %s SET assigned_user='' WHERE id=$1 RETURNING %s;", s.Table, returnValues)
+	query := fmt.Sprintf("UPDATE %s SET assigned_user='', assignment_date=NULL WHERE id=$1 RETURNING %s;", s.Table, returnValues)
 	return s.execQuery(query, taskId)
 }
 
+// getTaskIdsWithExpiredAssignment returns the IDs of all tasks which have been assigned for longer than the assignment
+// timeout of their project.
+func (s *Store) getTaskIdsWithExpiredAssignment(now time.Time) ([]string, error) {
+	query := fmt.Sprintf(`
+SELECT t.id
+FROM %s t, projects p
+WHERE
+	t.project_id = p.id AND
+	t.assigned_user <> '' AND
+	p.assignment_timeout > 0 AND
+	t.assignment_date + p.assignment_timeout * INTERVAL '1 hour' < $1
+ORDER BY t.id;`, s.Table)
+	s.LogQuery(query, now)
+
+	rows, err := s.tx.Query(query, now)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query to get tasks with expired assignment")
+	}
+	defer rows.Close()
+
+	taskIds := make([]string, 0)
+	for rows.Next() {
+		var taskId string
+		err = rows.Scan(&taskId)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan row for task id")
+		}
+		taskIds = append(taskIds, taskId)
+	}
+
+	return taskIds, nil
+}
+
 func (s *Store) setProcessPoints(taskId string, newPoints int) (*Task, error) {
 	query := fmt.Sprintf("UPDATE %s SET process_points=$1 WHERE id=$2 RETURNING %s;", s.Table, returnValues)
 	return s.execQuery(query, newPoints, taskId)
 }
 
+// addEvent stores the event for the given task. The project of the event is determined by the task.
+func (s *Store) addEvent(taskId string, eventType EventType, userId string, assignedUser string, previousProcessPoints int, processPoints int, creationDate time.Time) error {
+	query := fmt.Sprintf("INSERT INTO %s (project_id, task_id, type, user_id, assigned_user, previous_process_points, process_points, creation_date) SELECT project_id, id, $2, $3, $4, $5, $6, $7 FROM %s WHERE id = $1;", s.eventTable, s.Table)
+	s.LogQuery(query, taskId, eventType, userId, assignedUser, previousProcessPoints, processPoints, creationDate)
+
+	_, err := s.tx.Exec(query, taskId, eventType, userId, assignedUser, previousProcessPoints, processPoints, creationDate)
+	if err != nil {
+		return errors.Wrapf(err, "error adding %s event for task %s", eventType, taskId)
+	}
+
+	return nil
+}
+
+// getEvents returns all events of the task ordered from oldest to newest.
+func (s *Store) getEvents(taskId string) ([]*Event, error) {
+	query := fmt.Sprintf("SELECT id, task_id, type, user_id, assigned_user, previous_process_points, process_points, creation_date FROM %s WHERE task_id = $1 ORDER BY creation_date, id;", s.eventTable)
+	s.LogQuery(query, taskId)
+
+	rows, err := s.tx.Query(query, taskId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error executing query to get events of task %s", taskId)
+	}
+	defer rows.Close()
+
+	events := make([]*Event, 0)
+	for rows.Next() {
+		var e eventRow
+		err = rows.Scan(&e.id, &e.taskId, &e.eventType, &e.userId, &e.assignedUser, &e.previousProcessPoints, &e.processPoints, &e.creationDate)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan row into event")
+		}
+
+		events = append(events, &Event{
+			Id:                    strconv.Itoa(e.id),
+			TaskId:                strconv.Itoa(e.taskId),
+			Type:                  EventType(e.eventType),
+			UserId:                e.userId,
+			AssignedUser:          e.assignedUser,
+			PreviousProcessPoints: e.previousProcessPoints,
+			ProcessPoints:         e.processPoints,
+			CreationDate:          e.creationDate,
+		})
+	}
+
+	return events, nil
+}
+
+// getLastMapper returns the user who set the process points of the task last. The result is empty when nobody has set
+// process points on this task yet.
+func (s *Store) getLastMapper(taskId string) (string, error) {
+	query := fmt.Sprintf("SELECT user_id FROM %s WHERE task_id = $1 AND type = $2 ORDER BY id DESC LIMIT 1;", s.eventTable)
+	s.LogQuery(query, taskId, EventProcessPoints)
+
+	rows, err := s.tx.Query(query, taskId, EventProcessPoints)
+	if err != nil {
+		return "", errors.Wrapf(err, "error executing query to get last mapper of task %s", taskId)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", nil
+	}
+
+	var mapper string
+	err = rows.Scan(&mapper)
+	if err != nil {
+		return "", errors.Wrap(err, "could not scan row for last mapper")
+	}
+
+	return mapper, nil
+}
+
+// GetContributions sums up the recorded events of the project for each user who caused at least one event. Resetting
+// process points (e.g. by rejecting a task) is credited to the user who set the process points last. Splitting and
+// merging tasks as well as changes made by the system are no contributions.
+func (s *Store) GetContributions(projectId string) ([]*Contribution, error) {
+	query := fmt.Sprintf(`
+SELECT
+	credited_user,
+	COALESCE(SUM(process_points - previous_process_points) FILTER (WHERE type = ANY($2)), 0),
+	COUNT(DISTINCT task_id)
+FROM (
+	SELECT
+		e.task_id,
+		e.type,
+		e.previous_process_points,
+		e.process_points,
+		CASE WHEN e.type = ANY($3) THEN (
+			SELECT p.user_id
+			FROM %s p
+			WHERE p.task_id = e.task_id AND p.type = $4 AND p.id < e.id
+			ORDER BY p.id DESC
+			LIMIT 1
+		) ELSE e.user_id END AS credited_user
+	FROM %s e
+	WHERE e.project_id = $1 AND e.type <> ALL($5)
+) contributions
+WHERE credited_user IS NOT NULL AND credited_user <> $6
+GROUP BY credited_user
+ORDER BY credited_user;`, s.eventTable, s.eventTable)
+	s.LogQuery(query, projectId, processPointEventTypes, resetEventTypes, EventProcessPoints, replacementEventTypes, comment.SystemAuthorId)
+
+	rows, err := s.tx.Query(query, projectId, pq.Array(processPointEventTypes), pq.Array(resetEventTypes), EventProcessPoints, pq.Array(replacementEventTypes), comment.SystemAuthorId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error executing query to get contributions for project %s", projectId)
+	}
+	defer rows.Close()
+
+	contributions := make([]*Contribution, 0)
+	for rows.Next() {
+		var c Contribution
+		err = rows.Scan(&c.UserId, &c.ProcessPoints, &c.TouchedTasks)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan row into contribution")
+		}
+		contributions = append(contributions, &c)
+	}
+
+	return contributions, nil
+}
+
+// GetProgress sums up the process point changes of the project for each interval. Intervals without changes are not
+// part of the result.
+func (s *Store) GetProgress(projectId string, interval ProgressInterval) ([]*ProgressEntry, error) {
+	query := fmt.Sprintf(`
+SELECT
+	date_trunc($2, creation_date) AS interval_start,
+	SUM(process_points - previous_process_points)
+FROM %s
+WHERE project_id = $1 AND type = ANY($3)
+GROUP BY interval_start
+ORDER BY interval_start;`, s.eventTable)
+	s.LogQuery(query, projectId, interval, progressEventTypes)
+
+	rows, err := s.tx.Query(query, projectId, interval, pq.Array(progressEventTypes))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error executing query to get progress for project %s", projectId)
+	}
+	defer rows.Close()
+
+	progress := make([]*ProgressEntry, 0)
+	for rows.Next() {
+		var p ProgressEntry
+		err = rows.Scan(&p.Date, &p.ProcessPoints)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan row into progress entry")
+		}
+		progress = append(progress, &p)
+	}
+
+	return progress, nil
+}
+
+// taskGeometriesQuery is a common table expression containing the PostGIS geometries of all tasks of the project ($1).
+func (s *Store) taskGeometriesQuery() string {
+	return fmt.Sprintf(`
+WITH task_geometries AS (
+	SELECT id, geometry AS geom
+	FROM %s
+	WHERE project_id = $1
+)`, s.Table)
+}
+
+// getOverlaps returns all areas, where two tasks of the project overlap each other. Overlaps smaller than the given
+// minimum area (in square meters) are ignored.
+func (s *Store) getOverlaps(projectId string, minArea float64) ([]*Overlap, error) {
+	query := s.taskGeometriesQuery() + `
+SELECT a.id, b.id, ST_AsGeoJSON(overlap.geom), ST_Area(overlap.geom::geography) AS area
+FROM task_geometries a
+	JOIN task_geometries b ON a.id < b.id AND ST_Relate(a.geom, b.geom, '2********'),
+	LATERAL (SELECT ST_CollectionExtract(ST_Intersection(a.geom, b.geom), 3) AS geom) overlap
+WHERE ST_Area(overlap.geom::geography) >= $2
+ORDER BY a.id, b.id;`
+	s.LogQuery(query, projectId, minArea)
+
+	rows, err := s.tx.Query(query, projectId, minArea)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error executing query to get overlapping tasks of project %s", projectId)
+	}
+	defer rows.Close()
+
+	overlaps := make([]*Overlap, 0)
+	for rows.Next() {
+		var taskIdA, taskIdB string
+		var o Overlap
+		err = rows.Scan(&taskIdA, &taskIdB, &o.Geometry, &o.Area)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan row into overlap")
+		}
+		o.TaskIds = []string{taskIdA, taskIdB}
+		overlaps = append(overlaps, &o)
+	}
+
+	return overlaps, nil
+}
+
+// getGaps returns all areas within the given boundary, which are not covered by a task of the project. When no boundary
+// (a GeoJSON geometry) is given, the boundary of the project is used and, if the project has none, the convex hull of
+// all tasks. Gaps smaller than the given minimum area (in square meters) are ignored.
+func (s *Store) getGaps(projectId string, boundary string, minArea float64) ([]*Gap, error) {
+	query := s.taskGeometriesQuery() + `,
+coverage AS (
+	SELECT ST_Union(geom) AS geom FROM task_geometries
+),
+boundary AS (
+	SELECT COALESCE(
+		ST_MakeValid(ST_GeomFromGeoJSON(NULLIF($2, ''))),
+		(SELECT boundary FROM projects WHERE id = $1),
+		ST_ConvexHull(coverage.geom)
+	) AS geom FROM coverage
+)
+SELECT ST_AsGeoJSON(gap.geom), ST_Area(gap.geom::geography) AS area
+FROM boundary, coverage,
+	LATERAL ST_Dump(ST_CollectionExtract(ST_Difference(boundary.geom, coverage.geom), 3)) gap
+WHERE coverage.geom IS NOT NULL AND ST_Area(gap.geom::geography) >= $3
+ORDER BY area DESC;`
+	s.LogQuery(query, projectId, boundary, minArea)
+
+	rows, err := s.tx.Query(query, projectId, boundary, minArea)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error executing query to get gaps between tasks of project %s", projectId)
+	}
+	defer rows.Close()
+
+	gaps := make([]*Gap, 0)
+	for rows.Next() {
+		var g Gap
+		err = rows.Scan(&g.Geometry, &g.Area)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan row into gap")
+		}
+		gaps = append(gaps, &g)
+	}
+
+	return gaps, nil
+}
+
+func (s *Store) update(taskId string, maxProcessPoints int, processPoints int, geometry string) (*Task, error) {
+	query := fmt.Sprintf(`UPDATE %s
+SET max_process_points=$1, process_points=$2, geometry=ST_Multi(ST_GeomFromGeoJSON($3::jsonb->'geometry')), properties=NULLIF($3::jsonb->'properties', 'null'::jsonb)
+WHERE id=$4
+RETURNING %s;`, s.Table, returnValues)
+	return s.execQuery(query, maxProcessPoints, processPoints, geometry, taskId)
+}
+
+func (s *Store) setValidationState(taskId string, state ValidationState, mapper string, validator string) (*Task, error) {
+	query := fmt.Sprintf("UPDATE %s SET validation_state=$1, mapper=$2, validator=$3 WHERE id=$4 RETURNING %s;", s.Table, returnValues)
+	return s.execQuery(query, state, mapper, validator, taskId)
+}
+
+// delete removes the tasks and their comment lists including all comments.
 func (s *Store) delete(taskIds []string) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE id=ANY($1)", s.Table)
+	query := fmt.Sprintf("DELETE FROM %s WHERE id=ANY($1) RETURNING comment_list_id", s.Table)
 
 	s.LogQuery(query, taskIds)
-	_, err := s.tx.Exec(query, pq.Array(taskIds))
+	rows, err := s.tx.Query(query, pq.Array(taskIds))
+	if err != nil {
+		return errors.Wrapf(err, "error deleting tasks %v", taskIds)
+	}
+
+	var commentListIds []string
+	for rows.Next() {
+		var commentListId sql.NullString
+		err = rows.Scan(&commentListId)
+		if err != nil {
+			rows.Close()
+			return errors.Wrap(err, "could not scan row for comment list id")
+		}
+
+		if commentListId.Valid {
+			commentListIds = append(commentListIds, commentListId.String)
+		}
+	}
+
+	err = rows.Close()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return s.commentStore.DeleteCommentLists(commentListIds)
+}
+
+// getCommentListIds returns the IDs of the comment lists of the given tasks mapped by their task ID.
+func (s *Store) getCommentListIds(taskIds []string) (map[string]string, error) {
+	query := fmt.Sprintf("SELECT id, comment_list_id FROM %s WHERE id = ANY($1);", s.Table)
+	s.LogQuery(query, taskIds)
+
+	rows, err := s.tx.Query(query, pq.Array(taskIds))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error executing query to get comment list ids for tasks %v", taskIds)
+	}
+	defer rows.Close()
+
+	commentListIds := make(map[string]string)
+	for rows.Next() {
+		var taskId, commentListId string
+		err = rows.Scan(&taskId, &commentListId)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan row for comment list id")
+		}
+		commentListIds[taskId] = commentListId
+	}
+
+	for _, taskId := range taskIds {
+		if _, ok := commentListIds[taskId]; !ok {
+			return nil, errors.Errorf("could not find comment list of task %s", taskId)
+		}
+	}
+
+	return commentListIds, nil
 }
 
 func (s *Store) getCommentListId(taskId string) (string, error) {
@@ -210,7 +806,7 @@ func (s *Store) execQuery(query string, params ...interface{}) (*Task, error) {
 // rowToTask turns the current row into a Task object. This does not close the row.
 func (s *Store) rowToTask(rows *sql.Rows) (*Task, *taskRow, error) {
 	var task taskRow
-	err := rows.Scan(&task.id, &task.processPoints, &task.maxProcessPoints, &task.geometry, &task.assignedUser, &task.commentListId)
+	err := rows.Scan(&task.id, &task.processPoints, &task.maxProcessPoints, &task.geometry, &task.name, &task.assignedUser, &task.commentListId, &task.validationState, &task.mapper, &task.validator, &task.projectId)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "could not scan rows")
 	}
@@ -222,15 +818,10 @@ func (s *Store) rowToTask(rows *sql.Rows) (*Task, *taskRow, error) {
 	result.MaxProcessPoints = task.maxProcessPoints
 	result.AssignedUser = task.assignedUser
 	result.Geometry = task.geometry
-
-	feature, err := geojson.UnmarshalFeature([]byte(result.Geometry))
-	if feature == nil || err != nil {
-		return nil, nil, errors.Wrapf(err, "could not unmarshal task geometry '%s' from row", result.Geometry)
-	}
-	name, err := feature.PropertyString("name")
-	if err == nil {
-		result.Name = name
-	}
+	result.Name = task.name
+	result.ValidationState = ValidationState(task.validationState)
+	result.Mapper = task.mapper
+	result.Validator = task.validator
 
 	return &result, &task, nil
 }
@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/hauke96/sigolo"
-	_ "github.com/lib/pq" // Make driver "postgres" usable
 	"github.com/pkg/errors"
 	"os"
 	"testing"
@@ -28,7 +27,7 @@ func (h *Helper) InitWithDummyData(dbUsername, dbPassword, dbDatabase string) {
 	sigolo.Info("Add database dummy data")
 
 	var err error
-	h.db, err = sql.Open("postgres", fmt.Sprintf("user=%s password=%s dbname=%s sslmode=disable", dbUsername, dbPassword, dbDatabase))
+	h.db, err = sql.Open(countingDriverName, fmt.Sprintf("user=%s password=%s dbname=%s sslmode=disable", dbUsername, dbPassword, dbDatabase))
 	if err != nil {
 		sigolo.Fatal("Unable to connect to database: %s", err.Error())
 	}
@@ -56,7 +55,7 @@ func (h *Helper) NewTransaction() *sql.Tx {
 	return h.Tx
 }
 
-func (h *Helper) Run(t *testing.T, testFunc func() error) {
+func (h *Helper) Run(t testing.TB, testFunc func() error) {
 	if h.Setup != nil {
 		h.Setup()
 	}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+
+	"github.com/lib/pq"
+)
+
+const countingDriverName = "postgres-counting"
+
+// queryCount is the number of queries and statements sent to the database via the counting driver so far.
+var queryCount int64
+
+func init() {
+	sql.Register(countingDriverName, &countingDriver{})
+}
+
+// countingDriver wraps the postgres driver and counts every query and statement executed on its connections. This
+// is used to check the number of queries an operation needs.
+type countingDriver struct {
+	pq.Driver
+}
+
+func (d *countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{conn}, nil
+}
+
+type countingConn struct {
+	driver.Conn
+}
+
+func (c *countingConn) Prepare(query string) (driver.Stmt, error) {
+	atomic.AddInt64(&queryCount, 1)
+	return c.Conn.Prepare(query)
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		// Falls back to Prepare, which counts the query
+		return nil, driver.ErrSkip
+	}
+
+	atomic.AddInt64(&queryCount, 1)
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		// Falls back to Prepare, which counts the statement
+		return nil, driver.ErrSkip
+	}
+
+	atomic.AddInt64(&queryCount, 1)
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *countingConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// QueryCount returns the number of queries and statements sent to the database by all test helpers so far.
+func (h *Helper) QueryCount() int {
+	return int(atomic.LoadInt64(&queryCount))
+}
@@ -0,0 +1,15 @@
+package util
+
+// InvalidRequestError is caused by a request of the user, which can't be fulfilled. Examples are invalid parameters or
+// a request conflicting with the current state (e.g. adding a user twice). It's not caused by a problem of the server.
+type InvalidRequestError struct {
+	Err error
+}
+
+func (e *InvalidRequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidRequestError) Unwrap() error {
+	return e.Err
+}
@@ -30,6 +30,21 @@ func GetIntParam(param string, r *http.Request) (int, error) {
 	return strconv.Atoi(valueString)
 }
 
+// GetOptionalIntParam returns the given default value when the parameter is not specified.
+func GetOptionalIntParam(param string, r *http.Request, defaultValue int) (int, error) {
+	valueString := r.FormValue(param)
+	if strings.TrimSpace(valueString) == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(valueString)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parameter '%s' is not an integer", param)
+	}
+
+	return value, nil
+}
+
 func ResponseBadRequest(w http.ResponseWriter, logger *Logger, err error) {
 	ErrorResponse(w, logger, err, http.StatusBadRequest)
 }
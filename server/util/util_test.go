@@ -107,6 +107,53 @@ func TestGetIntParam(t *testing.T) {
 	}
 }
 
+func TestGetOptionalIntParam(t *testing.T) {
+	params := make(map[string][]string)
+	params["foo"] = []string{"123"}
+	params["bar"] = []string{"abc"}
+
+	r := &http.Request{
+		Form: params,
+	}
+
+	// Existing param
+
+	param, err := GetOptionalIntParam("foo", r, 42)
+	if err != nil {
+		t.Errorf("Getting params should work: %s", err.Error())
+		t.Fail()
+		return
+	}
+	if param != 123 {
+		t.Errorf("Param should have value '123'")
+		t.Fail()
+		return
+	}
+
+	// Not existing param
+
+	param, err = GetOptionalIntParam("utini", r, 42)
+	if err != nil {
+		t.Errorf("Getting not existing params should work: %s", err.Error())
+		t.Fail()
+		return
+	}
+	if param != 42 {
+		t.Errorf("Param for key 'utini' should have the default value '42'")
+		t.Fail()
+		return
+	}
+
+	// Invalid param
+
+	_, err = GetOptionalIntParam("bar", r, 42)
+	if err == nil {
+		t.Error("Getting non-integer params should not work")
+		t.Fail()
+		return
+	}
+}
+
 func TestResponseErrors(t *testing.T) {
 	logger := NewLogger()
 